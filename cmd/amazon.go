@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JINZO631/freeedom/pkg/amazon"
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		afterDate  string
+		beforeDate string
+		outputDir  string
+		creds      credential.Options
+	)
+
+	// amazonCmd represents the amazon command
+	var amazonCmd = &cobra.Command{
+		Use:   "amazon",
+		Short: "Amazon.co.jpの注文履歴から領収書PDFと出品者の請求書をダウンロードします。",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := amazon.Run(context.Background(), creds, afterDate, beforeDate, outputDir); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(amazonCmd)
+	amazonCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始年月 (format: 202401)")
+	amazonCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了年月 (format: 202401)")
+	amazonCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	registerCredentialFlags(amazonCmd.Flags(), &creds)
+
+	amazonCmd.MarkFlagRequired("after")
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// authServices auth コマンドで扱えるサービス
+var authServices = []string{"gmail"}
+
+// authScopes --scope で指定できる権限の名前
+var authScopes = map[string]string{
+	"readonly": gmailapi.ReadonlyScope,
+	"modify":   gmailapi.ModifyScope,
+}
+
+func init() {
+	var (
+		oauthClientJSON string
+		accounts        []string
+		loginScope      string
+		scopeName       string
+	)
+
+	var authCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "保存している認証情報 (GmailAPIのトークン) を管理します。",
+		Long:  `サービスを省略した場合は gmail を対象にします。`,
+	}
+
+	var loginCmd = &cobra.Command{
+		Use:   "login [サービス]",
+		Short: "ブラウザで認証し、トークンを保存します。保存済みのトークンは置き換えます。",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkAuthService(args); err != nil {
+				log.Fatalln(err)
+			}
+			scope, err := authScope(loginScope)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			for _, account := range defaultAccounts(accounts) {
+				email, err := gmailapi.Login(context.Background(), oauthClientJSON, account, scope)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Println(color.GreenString("✓"), account, email, "でログインしました。")
+			}
+		},
+	}
+
+	var statusCmd = &cobra.Command{
+		Use:   "status [サービス]",
+		Short: "保存しているトークンのアカウント・権限・有効期限を表示します。",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkAuthService(args); err != nil {
+				log.Fatalln(err)
+			}
+			if err := authStatus(accounts); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	var logoutCmd = &cobra.Command{
+		Use:   "logout [サービス]",
+		Short: "保存しているトークンを取り消して削除します。",
+		Long:  `--scope を省略した場合は全ての権限のトークンを削除します。`,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkAuthService(args); err != nil {
+				log.Fatalln(err)
+			}
+			scopes, err := authScopesOrAll(scopeName)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			failed := 0
+			for _, account := range defaultAccounts(accounts) {
+				ok := true
+				for _, scope := range scopes {
+					if err := gmailapi.Logout(context.Background(), account, scope); err != nil {
+						fmt.Println(color.RedString("×"), account, err)
+						ok = false
+					}
+				}
+				if !ok {
+					failed++
+					continue
+				}
+				fmt.Println(color.GreenString("✓"), account, "からログアウトしました。")
+			}
+			if failed > 0 {
+				log.Fatalf("%d件のアカウントのログアウトに失敗しました。", failed)
+			}
+		},
+	}
+
+	var refreshCmd = &cobra.Command{
+		Use:   "refresh [サービス]",
+		Short: "保存しているトークンをリフレッシュします。失効している場合は auth login で認証し直してください。",
+		Long:  `--account を省略した場合はトークンが保存されている全てのアカウントをリフレッシュします。`,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkAuthService(args); err != nil {
+				log.Fatalln(err)
+			}
+			if err := authRefresh(context.Background(), oauthClientJSON, accounts, scopeName); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(loginCmd, statusCmd, logoutCmd, refreshCmd)
+
+	for _, c := range []*cobra.Command{loginCmd, refreshCmd} {
+		c.Flags().StringVarP(&oauthClientJSON, "gmail-api-credentials-path", "g", "", "GmailAPIのクライアントJSONのパス")
+		c.MarkFlagRequired("gmail-api-credentials-path")
+	}
+	for _, c := range []*cobra.Command{loginCmd, statusCmd, logoutCmd, refreshCmd} {
+		c.Flags().StringSliceVar(&accounts, "account", nil, "対象にするアカウント名")
+	}
+	loginCmd.Flags().StringVar(&loginScope, "scope", "readonly", "権限 (readonly, modify)")
+	logoutCmd.Flags().StringVar(&scopeName, "scope", "", "権限 (readonly, modify)")
+	refreshCmd.Flags().StringVar(&scopeName, "scope", "", "権限 (readonly, modify)")
+}
+
+// checkAuthService 引数のサービスが auth コマンドで扱えるか確認する
+func checkAuthService(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	for _, s := range authServices {
+		if args[0] == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("対応していないサービスです: %s (対応: %v)", args[0], authServices)
+}
+
+// authScope 権限の名前からGmailAPIのスコープを返す
+func authScope(name string) (string, error) {
+	scope, ok := authScopes[name]
+	if !ok {
+		return "", fmt.Errorf("権限は readonly か modify を指定してください: %s", name)
+	}
+	return scope, nil
+}
+
+// authScopesOrAll 権限の指定がなければ全ての権限を返す
+func authScopesOrAll(name string) ([]string, error) {
+	if name == "" {
+		return gmailapi.Scopes(), nil
+	}
+	scope, err := authScope(name)
+	if err != nil {
+		return nil, err
+	}
+	return []string{scope}, nil
+}
+
+// defaultAccounts アカウントの指定がなければ default アカウントを返す
+func defaultAccounts(accounts []string) []string {
+	if len(accounts) == 0 {
+		return []string{gmailapi.DefaultAccount}
+	}
+	return accounts
+}
+
+// scopeLabel スコープの表示名
+func scopeLabel(scope string) string {
+	for name, s := range authScopes {
+		if s == scope {
+			return name
+		}
+	}
+	return scope
+}
+
+// authStatus 保存しているトークンの状態を表で出力する
+func authStatus(accounts []string) error {
+	accounts, err := gmailapi.ResolveAccounts(accounts)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "サービス\tアカウント\tメールアドレス\t権限\t有効期限\tリフレッシュトークン")
+	for _, account := range accounts {
+		statuses, err := gmailapi.Status(account)
+		if err != nil {
+			return err
+		}
+		if len(statuses) == 0 {
+			fmt.Fprintf(w, "gmail\t%s\t\t%s\t\t\n", account, color.YellowString("未ログイン"))
+			continue
+		}
+
+		for _, s := range statuses {
+			expiry := s.Expiry.Local().Format("2006-01-02 15:04")
+			if s.Expiry.Before(time.Now()) {
+				expiry += " (期限切れ)"
+			}
+			refresh := color.GreenString("あり")
+			if !s.HasRefreshToken {
+				refresh = color.RedString("なし")
+			}
+			fmt.Fprintf(w, "gmail\t%s\t%s\t%s\t%s\t%s\n", s.Account, s.Email, scopeLabel(s.Scope), expiry, refresh)
+		}
+	}
+	return w.Flush()
+}
+
+// authRefresh 保存しているトークンをリフレッシュする
+func authRefresh(ctx context.Context, oauthClientJSON string, accounts []string, scopeName string) error {
+	accounts, err := gmailapi.ResolveAccounts(accounts)
+	if err != nil {
+		return err
+	}
+	scopes, err := authScopesOrAll(scopeName)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		for _, scope := range scopes {
+			has, err := gmailapi.HasGmailAPIToken(account, scope)
+			if err != nil {
+				return err
+			}
+			if !has {
+				continue
+			}
+
+			token, err := gmailapi.Refresh(ctx, oauthClientJSON, account, scope)
+			if err != nil {
+				return fmt.Errorf("アカウント %s: %w", account, err)
+			}
+			fmt.Println(color.GreenString("✓"), account, scopeLabel(scope), "有効期限:", token.Expiry.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return nil
+}
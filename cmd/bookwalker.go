@@ -3,8 +3,10 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/JINZO631/freeedom/pkg/bookwalker"
+	"github.com/JINZO631/freeedom/pkg/credential"
 	"github.com/spf13/cobra"
 )
 
@@ -13,15 +15,26 @@ func init() {
 		afterDate  string
 		beforeDate string
 		outputDir  string
+		creds      credential.Options
+		profile    string
+		include    []string
+		region     string
 	)
 
 	// bookwalkerCmd represents the bookwalker command
 	var bookwalkerCmd = &cobra.Command{
 		Use:   "bookwalker",
 		Short: "BOOLWALKERから領収書PDFをダウンロードします。",
-		Long:  ``,
+		Long: `--region global でグローバルストア (英語版の BOOK☆WALKER) の領収書をダウンロードします。
+グローバルストアへの対応は試験的です。ログインページ・決済履歴ページのURLとセレクタは実際のページで確認できていないため、
+取得できない場合は設定ディレクトリの sites/bookwalker-global.yaml にサイト定義を書いてください。`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := bookwalker.Run(context.Background(), afterDate, beforeDate, outputDir); err != nil {
+			opts, err := siteOptions(creds, profile, include)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := bookwalker.Run(context.Background(), region, opts, afterDate, beforeDate, outputDir); err != nil {
 				fmt.Println(err)
 			}
 		},
@@ -31,6 +44,10 @@ func init() {
 	bookwalkerCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始年月 (format: 202401)")
 	bookwalkerCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了年月 (format: 202401)")
 	bookwalkerCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	registerCredentialFlags(bookwalkerCmd.Flags(), &creds)
+	registerProfileFlag(bookwalkerCmd.Flags(), &profile)
+	registerIncludeFlag(bookwalkerCmd.Flags(), &include)
+	bookwalkerCmd.Flags().StringVar(&region, "region", bookwalker.RegionJP, fmt.Sprintf("ストアの地域 (%s。%s は試験的)", strings.Join(bookwalker.Regions(), ", "), bookwalker.RegionGlobal))
 
 	bookwalkerCmd.MarkFlagRequired("after")
 }
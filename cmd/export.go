@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/export"
+	"github.com/JINZO631/freeedom/pkg/journal"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/rules"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		format     string
+		receiptDir string
+		rulesPath  string
+		afterDate  string
+		beforeDate string
+		outputPath string
+	)
+
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "保存した領収書のメタデータから会計ソフト (freee・マネーフォワード・弥生) のインポート用ファイルを作成します。",
+		Long: `--dir に保存されている領収書のメタデータを読み込み、期間内の領収書を仕訳に変換して出力します。
+勘定科目・税区分・取引先・家事按分はルールの設定ファイル (デフォルト: 設定ディレクトリの rules.yaml) で指定します。
+外貨建ての領収書は円に換算できないため仕訳に含めず、警告を表示します。`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runExport(format, receiptDir, rulesPath, afterDate, beforeDate, outputPath); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&format, "format", "f", "freee-csv", fmt.Sprintf("出力形式 (%s)", strings.Join(export.Formats(), ", ")))
+	exportCmd.Flags().StringVarP(&receiptDir, "dir", "d", ".", "領収書のメタデータが保存されているディレクトリ")
+	exportCmd.Flags().StringVarP(&rulesPath, "rules", "r", "", "ルールの設定ファイルのパス")
+	exportCmd.Flags().StringVarP(&afterDate, "after", "a", "", "対象期間の開始日 (format: 2024-01-01)")
+	exportCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "対象期間の終了日 (format: 2024-01-31)")
+	exportCmd.Flags().StringVarP(&outputPath, "output", "o", "", "出力先ファイル (デフォルト: 標準出力)")
+}
+
+func runExport(format, receiptDir, rulesPath, afterDate, beforeDate, outputPath string) error {
+	receipts, err := receipt.LoadDir(receiptDir)
+	if err != nil {
+		return err
+	}
+
+	rs, err := rules.Load(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	receipts = receipt.Filter(receipts, afterDate, beforeDate)
+	entries, err := journal.NewEntries(receipts, rs)
+	if err != nil {
+		return err
+	}
+	// 外貨建ての領収書は円に換算できないため、手入力が必要なことを知らせる
+	for _, r := range journal.ForeignReceipts(receipts) {
+		fmt.Fprintln(os.Stderr, color.YellowString("!"), "外貨建ての領収書は仕訳に含めません:", r.Provider+"-"+r.ID, r.Date, receipt.FormatMoney(r.Total, r.Currency))
+	}
+
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := export.Write(w, format, entries); err != nil {
+		return err
+	}
+
+	if outputPath != "" {
+		fmt.Println("仕訳を出力しました。 件数:", len(entries), outputPath)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"github.com/JINZO631/freeedom/pkg/gmailreceipt"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		configPath  string
+		ruleNames   []string
+		afterDate   string
+		beforeDate  string
+		outputDir   string
+		credentials gmailapi.Credentials
+		labels      gmailapi.LabelOptions
+	)
+	var gmailCmd = &cobra.Command{
+		Use:   "gmail",
+		Short: "Gmailに届いた請求書メールの添付ファイルを、設定ファイルのルールに従ってダウンロードします。",
+		Long: `設定ファイル (デフォルト: 設定ディレクトリの gmail.yaml) にメールの検索クエリ・差出人・添付ファイル名・メタデータを取り出す正規表現を指定してください。
+GmailAPIのOAuthクライアント (またはサービスアカウント) は ubereats コマンドと同じものを使います。`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := gmailreceipt.Run(context.Background(), &credentials, configPath, ruleNames, labels, afterDate, beforeDate, outputDir); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+	rootCmd.AddCommand(gmailCmd)
+
+	gmailCmd.Flags().StringVarP(&credentials.OAuthClientJSON, "gmail-api-credentials-path", "g", "", "GmailAPIのクライアントJSONのパス")
+	gmailCmd.Flags().StringVarP(&configPath, "config", "c", "", "Gmailの設定ファイルのパス")
+	gmailCmd.Flags().StringSliceVarP(&ruleNames, "rule", "r", nil, "対象にするルールの名前 (デフォルト: 全て)")
+	gmailCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始日 (format: 2024-01-01)")
+	gmailCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了日 (format: 2024-01-01)")
+	gmailCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	gmailCmd.Flags().StringSliceVar(&credentials.Accounts, "gmail-account", nil, "対象にするGmailのアカウント名 (デフォルト: トークンが保存されている全てのアカウント)")
+	registerServiceAccountFlags(gmailCmd.Flags(), &credentials.ServiceAccountKey, &credentials.Impersonate)
+	gmailCmd.Flags().BoolVar(&labels.Mark, "gmail-label", false, "処理したメールに freeedom/processed か freeedom/failed のラベルを付ける (Gmailの変更の権限で認証します)")
+	gmailCmd.Flags().BoolVar(&labels.SkipProcessed, "skip-processed", false, "freeedom/processed のラベルが付いたメールを検索から除く")
+
+	gmailCmd.MarkFlagsOneRequired("gmail-api-credentials-path", "gmail-service-account-key")
+	gmailCmd.MarkFlagRequired("after")
+	gmailCmd.MarkFlagRequired("before")
+}
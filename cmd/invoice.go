@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/JINZO631/freeedom/pkg/invoice"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var receiptDir string
+
+	var invoiceCmd = &cobra.Command{
+		Use:   "invoice",
+		Short: "適格請求書発行事業者の登録番号(T番号)を確認します。",
+		Long:  ``,
+	}
+
+	var importCmd = &cobra.Command{
+		Use:   "import [公表データCSV...]",
+		Short: "国税庁の適格請求書発行事業者公表システムからダウンロードした公表データ(CSV)を取り込みます。",
+		Long: `https://www.invoice-kohyo.nta.go.jp/download/ から全件データ(CSV形式)をダウンロードし、展開したCSVファイルを指定してください。
+差分データも取り込めます。取り込み済みのデータは残し、同じ登録番号の行だけを新しいデータで置き換えます。`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			count, err := invoice.Import(args)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Println("公表データを取り込みました。 件数:", count)
+		},
+	}
+
+	var checkCmd = &cobra.Command{
+		Use:   "check [登録番号...]",
+		Short: "領収書の登録番号のチェックデジットを検証し、公表データに登録されている名称を表示します。",
+		Long:  `登録番号を指定しない場合は --dir に保存されている領収書のメタデータを検証します。`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkInvoice(args, receiptDir); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(invoiceCmd)
+	invoiceCmd.AddCommand(importCmd)
+	invoiceCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringVarP(&receiptDir, "dir", "d", ".", "領収書のメタデータが保存されているディレクトリ")
+}
+
+// checkInvoice 登録番号を検証して結果を表で出力する
+func checkInvoice(numbers []string, receiptDir string) error {
+	receipts := []*receipt.Receipt{}
+	if len(numbers) > 0 {
+		for _, number := range numbers {
+			receipts = append(receipts, &receipt.Receipt{RegistrationNumber: number})
+		}
+	} else {
+		loaded, err := receipt.LoadDir(receiptDir)
+		if err != nil {
+			return err
+		}
+		receipts = loaded
+	}
+
+	lookupNumbers := []string{}
+	for _, r := range receipts {
+		if r.RegistrationNumber != "" {
+			lookupNumbers = append(lookupNumbers, r.RegistrationNumber)
+		}
+	}
+
+	// 公表データがインポートされていない場合はチェックデジットの検証のみ行う
+	registrants, err := invoice.Lookup(lookupNumbers)
+	if errors.Is(err, invoice.ErrRegistryNotImported) {
+		fmt.Println(color.YellowString("!"), err)
+	} else if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t発行者\t登録番号\t結果\t登録名称")
+	for _, r := range receipts {
+		result, name := checkRegistrationNumber(r.RegistrationNumber, registrants)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Vendor, r.RegistrationNumber, result, name)
+	}
+	return w.Flush()
+}
+
+// checkRegistrationNumber 登録番号1件の検証結果と登録名称を返す
+func checkRegistrationNumber(number string, registrants map[string]*invoice.Registrant) (string, string) {
+	if number == "" {
+		return color.RedString("登録番号なし"), ""
+	}
+	if err := invoice.Validate(number); err != nil {
+		return color.RedString("チェックデジット不一致"), ""
+	}
+	if registrants == nil {
+		return color.GreenString("OK"), ""
+	}
+
+	registrant, ok := registrants[number]
+	if !ok {
+		return color.RedString("未登録"), ""
+	}
+	if !registrant.Active() {
+		return color.RedString("取消・失効"), registrant.Name
+	}
+	return color.GreenString("OK"), registrant.Name
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"github.com/JINZO631/freeedom/pkg/mailsource"
+	"github.com/JINZO631/freeedom/pkg/prompt"
+	"github.com/spf13/pflag"
+)
+
+// imapPasswordEnv IMAPのパスワードを渡す環境変数
+const imapPasswordEnv = "FREEEDOM_IMAP_PASSWORD"
+
+// mailSourceFlags メールを読むコマンドに共通の、メールボックスを選ぶフラグ
+type mailSourceFlags struct {
+	gmailOAuthClientJSON   string
+	imap                   mailsource.IMAPConfig
+	gmailAccounts          []string
+	gmailServiceAccountKey string
+	gmailImpersonate       []string
+	gmailLabels            gmailapi.LabelOptions
+	incremental            bool
+	mbox                   string
+	emlDir                 string
+}
+
+func (f *mailSourceFlags) register(flags *pflag.FlagSet) {
+	flags.StringVarP(&f.gmailOAuthClientJSON, "gmail-api-credentials-path", "g", "", "GmailAPIのクライアントJSONのパス")
+	flags.StringSliceVar(&f.gmailAccounts, "gmail-account", nil, "対象にするGmailのアカウント名 (デフォルト: トークンが保存されている全てのアカウント)")
+	registerServiceAccountFlags(flags, &f.gmailServiceAccountKey, &f.gmailImpersonate)
+	flags.BoolVar(&f.gmailLabels.Mark, "gmail-label", false, "処理したメールに freeedom/processed か freeedom/failed のラベルを付ける (Gmailの変更の権限で認証します)")
+	flags.BoolVar(&f.gmailLabels.SkipProcessed, "skip-processed", false, "freeedom/processed のラベルが付いたメールを検索から除く (GmailAPIのみ)")
+	flags.BoolVar(&f.incremental, "incremental", false, "前回の実行以降にGmailに追加されたメールだけを検索する (GmailAPIのみ。追加されたメールは --after / --before の期間外でも処理する)")
+	flags.StringVar(&f.imap.Addr, "imap-server", "", "GmailAPIの代わりに使うIMAPサーバー (format: imap.example.com:993)")
+	flags.StringVar(&f.imap.Username, "imap-user", "", "IMAPのユーザー名")
+	flags.StringVar(&f.imap.Mailbox, "imap-mailbox", "INBOX", "IMAPで検索するメールボックス")
+	flags.BoolVar(&f.imap.TLS, "imap-tls", true, "IMAPサーバーに接続時からTLSで接続する (falseの場合はSTARTTLSを使う)")
+	flags.BoolVar(&f.imap.Insecure, "imap-insecure", false, "TLSにもSTARTTLSにも対応していないIMAPサーバーに平文でログインする (パスワードが暗号化されません)")
+	flags.StringVar(&f.mbox, "mbox", "", "メールボックスの代わりに読み込むmboxファイル (Google Takeoutなど)")
+	flags.StringVar(&f.emlDir, "eml-dir", "", "メールボックスの代わりに読み込む .eml ファイルのディレクトリ")
+}
+
+// source フラグに応じてローカルのファイル・IMAP・GmailAPIのメールボックスを作成する
+// IMAPのパスワードは環境変数 FREEEDOM_IMAP_PASSWORD か、なければ入力させる
+// name はメールを処理するコマンドの名前で、Gmailの差分同期の状態を保存するのに使う
+func (f *mailSourceFlags) source(ctx context.Context, name string) (mailsource.Source, error) {
+	if f.mbox != "" {
+		return mailsource.NewMbox(f.mbox), nil
+	}
+	if f.emlDir != "" {
+		return mailsource.NewEMLDir(f.emlDir), nil
+	}
+
+	if f.imap.Addr != "" {
+		config := f.imap
+		config.Password = os.Getenv(imapPasswordEnv)
+		if config.Password == "" {
+			fmt.Printf("IMAPのパスワード🔑 (%s): ", config.Username)
+			password, err := prompt.ReadPassword()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Println()
+			config.Password = string(password)
+		}
+		return mailsource.NewIMAP(config), nil
+	}
+
+	if f.gmailOAuthClientJSON == "" && f.gmailServiceAccountKey == "" {
+		return nil, errors.New("--gmail-api-credentials-path, --gmail-service-account-key, --imap-server, --mbox, --eml-dir のいずれかを指定してください")
+	}
+
+	// Gmailはアカウントごとにメールボックスを作り、まとめて1つのメールボックスとして扱う
+	return mailsource.NewGmailSource(ctx, f.gmailCredentials(), mailsource.GmailOptions{
+		Labels:      f.gmailLabels,
+		Incremental: f.incremental,
+		Name:        name,
+	})
+}
+
+// gmailCredentials フラグからGmailAPIの認証情報を作成する
+func (f *mailSourceFlags) gmailCredentials() *gmailapi.Credentials {
+	return &gmailapi.Credentials{
+		OAuthClientJSON:   f.gmailOAuthClientJSON,
+		Accounts:          f.gmailAccounts,
+		ServiceAccountKey: f.gmailServiceAccountKey,
+		Impersonate:       f.gmailImpersonate,
+	}
+}
+
+// registerServiceAccountFlags サービスアカウントでGmailに代理アクセスするためのフラグを登録する
+func registerServiceAccountFlags(flags *pflag.FlagSet, key *string, impersonate *[]string) {
+	flags.StringVar(key, "gmail-service-account-key", "", "OAuthクライアントの代わりに使う、ドメイン全体の委任を設定したサービスアカウントの鍵のJSONのパス (Google Workspace)")
+	flags.StringSliceVar(impersonate, "gmail-impersonate", nil, "サービスアカウントで代理アクセスするユーザーのメールアドレス (@で始めるとファイルから1行に1アドレスを読み込む)")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/JINZO631/freeedom/pkg/plugin"
+	"github.com/JINZO631/freeedom/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var pluginsCmd = &cobra.Command{
+		Use:   "plugins",
+		Short: "PATH上で見つかった外部プロバイダー (freeedom-provider-*) を一覧表示します。",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listPlugins(context.Background()); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+// addPluginCommands 外部プロバイダーをサブコマンドと sync のプロバイダーとして登録する
+// 組み込みのコマンドと同じ名前の外部プロバイダーは無視する
+func addPluginCommands() {
+	for _, p := range plugin.Discover() {
+		if cmd, _, err := rootCmd.Find([]string{p.Name}); err == nil && cmd != rootCmd {
+			continue
+		}
+
+		p := p
+		provider.Register(&provider.Provider{
+			Name:  p.Name,
+			Short: "外部プロバイダー " + p.Path,
+			Run: func(ctx context.Context, opts *provider.Options) error {
+				return plugin.Run(ctx, p, opts.After, opts.Before, opts.OutputDir)
+			},
+		})
+		rootCmd.AddCommand(newPluginCommand(p))
+	}
+}
+
+func newPluginCommand(p *plugin.Plugin) *cobra.Command {
+	var (
+		afterDate  string
+		beforeDate string
+		outputDir  string
+	)
+
+	var pluginCmd = &cobra.Command{
+		Use:   p.Name,
+		Short: fmt.Sprintf("外部プロバイダー %s から領収書PDFをダウンロードします。", p.Path),
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := plugin.Run(context.Background(), p, afterDate, beforeDate, outputDir); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	pluginCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始日 (format: 2024-01-01)")
+	pluginCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了日 (format: 2024-01-31)")
+	pluginCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+
+	pluginCmd.MarkFlagRequired("after")
+	pluginCmd.MarkFlagRequired("before")
+	return pluginCmd
+}
+
+// listPlugins 外部プロバイダーの名前と説明を表で出力する
+func listPlugins(ctx context.Context) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "名前\t説明\tパス")
+	for _, p := range plugin.Discover() {
+		description, err := p.Describe(ctx)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, err, p.Path)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, description.Description, p.Path)
+	}
+	return w.Flush()
+}
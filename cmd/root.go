@@ -14,6 +14,7 @@ var rootCmd = &cobra.Command{
 
 func Execute() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	addPluginCommands()
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
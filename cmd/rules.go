@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/rules"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		receiptDir string
+		rulesPath  string
+		afterDate  string
+		beforeDate string
+	)
+
+	var rulesCmd = &cobra.Command{
+		Use:   "rules",
+		Short: "勘定科目・税区分・家事按分を割り当てるルールを確認します。",
+		Long:  ``,
+	}
+
+	var testCmd = &cobra.Command{
+		Use:   "test",
+		Short: "期間内の領収書にルールを適用し、どのルールに当てはまったかを表示します。",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := testRules(receiptDir, rulesPath, afterDate, beforeDate); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(testCmd)
+	testCmd.Flags().StringVarP(&receiptDir, "dir", "d", ".", "領収書のメタデータが保存されているディレクトリ")
+	testCmd.Flags().StringVarP(&rulesPath, "rules", "r", "", "ルールの設定ファイルのパス")
+	testCmd.Flags().StringVarP(&afterDate, "after", "a", "", "対象期間の開始日 (format: 2024-01-01)")
+	testCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "対象期間の終了日 (format: 2024-01-31)")
+}
+
+// testRules 領収書ごとに当てはまったルールと割り当てた値を表で出力する
+func testRules(receiptDir, rulesPath, afterDate, beforeDate string) error {
+	receipts, err := receipt.LoadDir(receiptDir)
+	if err != nil {
+		return err
+	}
+
+	rs, err := rules.Load(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "購入日\t取得元\tID\t金額\tルール\t勘定科目\t税区分\t事業割合\tタグ\t備考")
+	for _, r := range receipt.Filter(receipts, afterDate, beforeDate) {
+		result, err := rs.Apply(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d%%\t%s\t%s\n",
+			r.Date, r.Provider, r.ID, receipt.FormatMoney(r.Total, r.Currency), result.Rule.Name,
+			result.AccountItem, result.TaxCategory, result.BusinessRatio,
+			strings.Join(result.Tags, ","), result.Memo)
+	}
+	return w.Flush()
+}
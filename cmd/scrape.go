@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		afterDate  string
+		beforeDate string
+		outputDir  string
+		creds      credential.Options
+		profile    string
+		include    []string
+	)
+
+	// scrapeCmd represents the scrape command
+	var scrapeCmd = &cobra.Command{
+		Use:   "scrape [サイト名 or サイト定義YAMLのパス]",
+		Short: "YAMLのサイト定義に従ってログインし、購入履歴から領収書PDFをダウンロードします。",
+		Long:  `サイト名を指定した場合は設定ディレクトリの sites/{サイト名}.yaml を読み込みます。`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			site, err := scraper.LoadSite(args[0])
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			opts, err := siteOptions(creds, profile, include)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := scraper.Run(context.Background(), site, opts, afterDate, beforeDate, outputDir); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(scrapeCmd)
+	scrapeCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始年月 (format: 202401)")
+	scrapeCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了年月 (format: 202401)")
+	scrapeCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	registerCredentialFlags(scrapeCmd.Flags(), &creds)
+	registerProfileFlag(scrapeCmd.Flags(), &profile)
+	registerIncludeFlag(scrapeCmd.Flags(), &include)
+
+	scrapeCmd.MarkFlagRequired("after")
+}
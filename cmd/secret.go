@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/JINZO631/freeedom/pkg/prompt"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/JINZO631/freeedom/pkg/secret"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var secretCmd = &cobra.Command{
+		Use:   "secret",
+		Short: "暗号化して保存している秘密情報 (トークン・ログイン情報・セッション) を管理します。",
+		Long: `秘密情報は設定ディレクトリに暗号化して保存します。
+鍵はデフォルトでは設定ディレクトリの secret.key に自動で作成します。
+secret passphrase でパスフレーズから導出した鍵に切り替えられます。`,
+	}
+
+	var passphraseCmd = &cobra.Command{
+		Use:   "passphrase",
+		Short: "保存している秘密情報をパスフレーズから導出した鍵で暗号化し直します。",
+		Long:  `以降の実行ではパスフレーズを入力するか、環境変数 ` + secret.PassphraseEnv + ` に指定してください。`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			passphrase, err := readNewPassphrase()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if err := secret.UsePassphrase(passphrase); err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Println(color.GreenString("✓"), "秘密情報をパスフレーズで暗号化し直しました。")
+		},
+	}
+
+	var forgetCmd = &cobra.Command{
+		Use:   "forget [サイト名]",
+		Short: "保存しているサイトのログイン情報とセッションを削除します。",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := scraper.Forget(args[0]); err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Println(color.GreenString("✓"), args[0], "のログイン情報とセッションを削除しました。")
+		},
+	}
+
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(passphraseCmd, forgetCmd)
+}
+
+// readNewPassphrase 新しいパスフレーズを確認のため2回入力させる
+func readNewPassphrase() ([]byte, error) {
+	fmt.Printf("新しいパスフレーズ🔑: ")
+	passphrase, err := prompt.ReadPassword()
+	fmt.Println()
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("パスフレーズを入力してください")
+	}
+
+	fmt.Printf("もう一度入力してください🔑: ")
+	confirm, err := prompt.ReadPassword()
+	fmt.Println()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirm) {
+		return nil, errors.New("パスフレーズが一致しません")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/spf13/pflag"
+)
+
+// registerCredentialFlags ブラウザでサイトにログインするコマンドのログイン情報のフラグを登録する
+func registerCredentialFlags(flags *pflag.FlagSet, opts *credential.Options) {
+	flags.StringVar(&opts.Helper, "credential-helper", "", "ログイン情報を取得する外部コマンド (git credential と同じ形式。省略時は環境変数 "+credential.HelperEnv+")")
+	flags.StringVar(&opts.Account, "credential-account", "", "ログインするアカウントのメールアドレス (アカウントごとにログイン情報とセッションを保存する)")
+}
+
+// registerProfileFlag 領収書の宛名と印刷設定のプロファイルのフラグを登録する
+func registerProfileFlag(flags *pflag.FlagSet, name *string) {
+	flags.StringVar(name, "profile", "", "領収書の宛名と印刷設定のプロファイル名 (設定ディレクトリの profiles.yaml。省略時は default)")
+}
+
+// registerIncludeFlag 購入以外に取得する支払の種類のフラグを登録する
+func registerIncludeFlag(flags *pflag.FlagSet, include *[]string) {
+	flags.StringSliceVar(include, "include", nil, "購入以外に取得する支払の種類 ("+strings.Join(scraper.PaymentTypes(), ", ")+")")
+}
+
+// siteOptions フラグの値からサイト定義で領収書を取得するときのオプションを作る
+func siteOptions(creds credential.Options, profile string, include []string) (*scraper.Options, error) {
+	p, err := scraper.LoadProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	return &scraper.Options{Credentials: creds, Profile: p, Include: include}, nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/amazon"
+	"github.com/JINZO631/freeedom/pkg/bookwalker"
+	"github.com/JINZO631/freeedom/pkg/gmailreceipt"
+	"github.com/JINZO631/freeedom/pkg/provider"
+	"github.com/JINZO631/freeedom/pkg/ubereats"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	opts := &provider.Options{}
+	var mailFlags mailSourceFlags
+	var uberTypes []string
+	var include []string
+	var bookwalkerRegion string
+
+	// 組み込みのプロバイダーを sync から使えるように登録する
+	provider.Register(&provider.Provider{
+		Name:  bookwalker.Provider,
+		Short: "BOOKWALKERの領収書",
+		Run: func(ctx context.Context, opts *provider.Options) error {
+			siteOpts, err := siteOptions(opts.SiteCredentials, opts.Profile, include)
+			if err != nil {
+				return err
+			}
+			return bookwalker.Run(ctx, bookwalkerRegion, siteOpts, provider.YearMonth(opts.After), provider.YearMonth(opts.Before), opts.OutputDir)
+		},
+	})
+	provider.Register(&provider.Provider{
+		Name:  amazon.Provider,
+		Short: "Amazon.co.jpの領収書",
+		Run: func(ctx context.Context, opts *provider.Options) error {
+			return amazon.Run(ctx, opts.SiteCredentials, provider.YearMonth(opts.After), provider.YearMonth(opts.Before), opts.OutputDir)
+		},
+	})
+	provider.Register(&provider.Provider{
+		Name:  ubereats.Provider,
+		Short: "UberEats・Uberの乗車の領収書 (Gmail)",
+		Run: func(ctx context.Context, opts *provider.Options) error {
+			src, err := mailFlags.source(ctx, ubereats.Provider)
+			if err != nil {
+				return err
+			}
+			return ubereats.Run(ctx, src, uberTypes, opts.After, opts.Before, opts.OutputDir)
+		},
+	})
+	provider.Register(&provider.Provider{
+		Name:  gmailreceipt.Provider,
+		Short: "Gmailに届いた請求書メールの添付ファイル",
+		Run: func(ctx context.Context, opts *provider.Options) error {
+			return gmailreceipt.Run(ctx, mailFlags.gmailCredentials(), "", nil, mailFlags.gmailLabels, opts.After, opts.Before, opts.OutputDir)
+		},
+	})
+
+	var syncCmd = &cobra.Command{
+		Use:   "sync [プロバイダー...]",
+		Short: "複数のプロバイダーから同じ期間の領収書をまとめてダウンロードします。",
+		Long:  `組み込みのプロバイダーに加えて、PATH上の freeedom-provider-* 外部プロバイダーも指定できます。`,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.GmailCredentials = mailFlags.gmailOAuthClientJSON
+			if err := runSync(context.Background(), args, opts); err != nil {
+				log.Fatalln(err)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringVarP(&opts.After, "after", "a", "", "検索範囲の開始日 (format: 2024-01-01)")
+	syncCmd.Flags().StringVarP(&opts.Before, "before", "b", "", "検索範囲の終了日 (format: 2024-01-31)")
+	syncCmd.Flags().StringVarP(&opts.OutputDir, "output-dir", "o", "", "出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	mailFlags.register(syncCmd.Flags())
+	registerCredentialFlags(syncCmd.Flags(), &opts.SiteCredentials)
+	registerProfileFlag(syncCmd.Flags(), &opts.Profile)
+	registerIncludeFlag(syncCmd.Flags(), &include)
+	syncCmd.Flags().StringVar(&bookwalkerRegion, "bookwalker-region", bookwalker.RegionJP, fmt.Sprintf("bookwalker のストアの地域 (%s。%s は試験的)", strings.Join(bookwalker.Regions(), ", "), bookwalker.RegionGlobal))
+	syncCmd.Flags().StringSliceVar(&uberTypes, "uber-type", []string{ubereats.Eats}, fmt.Sprintf("ubereats で対象にする領収書の種類 (%s)", strings.Join(ubereats.Types(), ", ")))
+
+	syncCmd.MarkFlagRequired("after")
+	syncCmd.MarkFlagRequired("before")
+}
+
+// runSync 指定したプロバイダーを順に実行する
+// 途中のプロバイダーが失敗しても残りは実行し、最後に失敗したものをまとめて返す
+func runSync(ctx context.Context, names []string, opts *provider.Options) error {
+	providers := []*provider.Provider{}
+	for _, name := range names {
+		p, err := provider.Find(name)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, p)
+	}
+
+	failed := []string{}
+	for _, p := range providers {
+		fmt.Println(color.CyanString("==>"), p.Name, p.Short)
+		if err := p.Run(ctx, opts); err != nil {
+			fmt.Println(color.RedString("×"), p.Name, err)
+			failed = append(failed, p.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("失敗したプロバイダーがあります: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/JINZO631/freeedom/pkg/ubereats"
 	"github.com/spf13/cobra"
@@ -10,27 +12,39 @@ import (
 
 func init() {
 	var (
-		gmailOAuthClientJSON string
-		afterDate            string
-		beforeDate           string
+		mailFlags  mailSourceFlags
+		afterDate  string
+		beforeDate string
+		outputDir  string
+		types      []string
 	)
 	var ubereatsCmd = &cobra.Command{
 		Use:   "ubereats",
-		Short: "Gmailに保存されているメールからUberEatsの領収書PDFをダウンロードします。PDFはChromeのダウンロードフォルダに保存されます。",
-		Long:  `GCP上でGmailAPIを有効化し、OAuthクライアントを作成し、そのクライアントのJSONをダウンロードして引数に指定してください。`,
+		Short: "Gmail (またはIMAPサーバー) に保存されているメールからUberEatsの領収書PDFをダウンロードします。PDFはメタデータと並べて出力先ディレクトリに保存されます。",
+		Long: `GCP上でGmailAPIを有効化し、OAuthクライアントを作成し、そのクライアントのJSONをダウンロードして引数に指定してください。
+Google Workspaceで社員のメールをまとめて取得する場合は、OAuthクライアントの代わりに --gmail-service-account-key と --gmail-impersonate を指定してください。
+GmailAPIを使わない場合は --imap-server と --imap-user を指定してください。パスワード (アプリパスワード) は環境変数 FREEEDOM_IMAP_PASSWORD か入力で渡します。
+Google Takeoutのmboxファイルや .eml ファイルから読み込む場合は --mbox か --eml-dir を指定してください (認証情報は不要です)。
+Uberの乗車 (タクシー等) の領収書も対象にする場合は --type eats,rides を指定してください。乗車地・降車地・距離・料金の内訳をメタデータに記録します。`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := ubereats.Run(context.Background(), gmailOAuthClientJSON, afterDate, beforeDate); err != nil {
+			ctx := context.Background()
+			src, err := mailFlags.source(ctx, ubereats.Provider)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if err := ubereats.Run(ctx, src, types, afterDate, beforeDate, outputDir); err != nil {
 				log.Fatalln(err)
 			}
 		},
 	}
 	rootCmd.AddCommand(ubereatsCmd)
 
-	ubereatsCmd.Flags().StringVarP(&gmailOAuthClientJSON, "gmail-api-credentials-path", "g", "", "GmailAPIのクライアントJSONのパス")
+	mailFlags.register(ubereatsCmd.Flags())
 	ubereatsCmd.Flags().StringVarP(&afterDate, "after", "a", "", "検索範囲の開始日 (format: 2024-01-01)")
 	ubereatsCmd.Flags().StringVarP(&beforeDate, "before", "b", "", "検索範囲の終了日 (format: 2024-01-01)")
+	ubereatsCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "メタデータとレポートの出力先ディレクトリ (デフォルト: カレントディレクトリ)")
+	ubereatsCmd.Flags().StringSliceVarP(&types, "type", "t", []string{ubereats.Eats}, fmt.Sprintf("対象にする領収書の種類 (%s)", strings.Join(ubereats.Types(), ", ")))
 
-	ubereatsCmd.MarkFlagRequired("gmail-api-credentials-path")
 	ubereatsCmd.MarkFlagRequired("after")
 	ubereatsCmd.MarkFlagRequired("before")
 }
@@ -0,0 +1,411 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/browser"
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/invoice"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Provider 領収書の取得元の名前
+const Provider = "amazon"
+
+// Vendor Amazonが販売した商品の領収書の発行者
+const Vendor = "アマゾンジャパン合同会社"
+
+// BaseURL Amazon.co.jpのURL (保存したHTMLを使って動作確認する場合はローカルのサーバーに差し替える)
+var BaseURL = "https://www.amazon.co.jp"
+
+// ordersPerPage 注文履歴ページ1ページあたりの注文数
+const ordersPerPage = 10
+
+// maxOrderPages 1年分の注文履歴を読むページ数の上限
+// ページ送りのパラメータが効かなくなった場合などに同じページを読み続けないようにする
+const maxOrderPages = 100
+
+// Run メイン処理
+// creds: ログイン情報の取得方法
+// after, before: 検索範囲の年月 (format: 202401)
+func Run(ctx context.Context, creds credential.Options, after, before, outputDir string) error {
+
+	// 取得対象の期間を生成
+	start, end, err := parsePeriod(after, before)
+	if err != nil {
+		return err
+	}
+
+	// chromedpの設定
+	ctx, cancel := browser.NewContext(ctx)
+	defer cancel()
+
+	// Amazonログイン
+	fmt.Println("Chromeを自動操作してAmazonにログインします。")
+	resolver := credential.New(Provider, BaseURL, creds)
+	c, err := resolver.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := Login(ctx, c.Email, c.Password); err != nil {
+		return err
+	}
+
+	// 2段階認証やパズルが入ることがあるのでそれを待機する
+	fmt.Println("ログインボタンを押してください。(2段階認証や画像認証が表示されたら手動で操作して完了してください)")
+	if err := WaitLogin(ctx); err != nil {
+		return err
+	}
+	if err := resolver.Approve(ctx, c); err != nil {
+		fmt.Println(color.YellowString("!"), err)
+	}
+
+	// 注文履歴を年ごとにページ送りして期間内の注文を取得
+	fmt.Println("注文履歴を取得します")
+	orders := []*Order{}
+	bar := progressbar.Default(int64(end.Year() - start.Year() + 1))
+	for year := start.Year(); year <= end.Year(); year++ {
+		found, err := GetYearOrders(ctx, year)
+		if err != nil {
+			return err
+		}
+		for _, o := range found {
+			if o.inPeriod(start, end) {
+				orders = append(orders, o)
+			}
+		}
+
+		bar.Add(1)
+	}
+
+	fmt.Println("注文履歴を取得しました 件数:", len(orders))
+
+	// 領収書と出品者の請求書をダウンロード
+	fmt.Println("領収書をダウンロードします")
+	report := receipt.NewReport(Provider, after, before)
+	downloadProgressBar := progressbar.Default(int64(len(orders)))
+	for _, o := range orders {
+		r, err := DownloadReceipt(ctx, o, outputDir)
+		if err != nil {
+			return err
+		}
+		report.Add(r)
+		downloadProgressBar.Add(1)
+	}
+
+	reportPath, err := report.Write(outputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Println("レポートを保存しました:", reportPath)
+
+	return nil
+}
+
+// pageTimeout ページの要素の表示を待つ時間
+const pageTimeout = 30 * time.Second
+
+// loginTimeout ログイン完了を待つ時間 (2段階認証や画像認証を手動で操作する時間を含む)
+const loginTimeout = 5 * time.Minute
+
+// loginSite ログイン完了の判定に使うサインインページの要素
+// ログイン後の待機は scraper.WaitLogin と同じ方法で、エラーメッセージ・画像認証・タイムアウトを判定する
+var loginSite = newLoginSite()
+
+func newLoginSite() *scraper.Site {
+	site := &scraper.Site{Name: Provider, DisplayName: "Amazon"}
+	site.Login.EmailField = `#ap_email`
+	site.Login.PasswordField = `#ap_password`
+	site.Login.Success = `#navbar`
+	site.Login.Error = `#auth-error-message-box .a-alert-content, #auth-warning-message-box .a-alert-content`
+	// 画像認証と2段階認証のコード入力は利用者が手動で操作する
+	site.Login.Captcha = `#auth-captcha-image, #captchacharacters, #auth-mfa-otpcode, #cvf-input-code`
+	site.Login.Timeout = loginTimeout
+	return site
+}
+
+// Login Chromeを自動操作してAmazonにログインする
+// 注文履歴ページを開くとサインインページにリダイレクトされるので、メールアドレスとパスワードを入力する
+func Login(ctx context.Context, email string, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, pageTimeout)
+	defer cancel()
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(BaseURL+"/gp/css/order-history"),            // 注文履歴ページ (未ログインならサインインページ) に遷移
+		chromedp.WaitVisible(`#ap_email`, chromedp.ByQuery),           // メールアドレスの入力欄が表示されるまで待機
+		chromedp.SendKeys(`#ap_email`, email, chromedp.ByQuery),       // メールアドレスを入力
+		chromedp.Click(`#continue`, chromedp.ByQuery),                 // 次へ進む
+		chromedp.WaitVisible(`#ap_password`, chromedp.ByQuery),        // パスワードの入力欄が表示されるまで待機
+		chromedp.SendKeys(`#ap_password`, password, chromedp.ByQuery), // パスワードを入力
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("Amazonのログインフォームが表示されませんでした: %w", scraper.ErrLoginTimeout)
+		}
+		return fmt.Errorf("Amazonのログインフォームに入力できませんでした: %w", err)
+	}
+	return nil
+}
+
+// WaitLogin ログイン完了まで待機する
+// エラーメッセージが表示された場合は scraper.ErrLoginFailed、時間内に完了しなかった場合は scraper.ErrLoginTimeout を返す
+func WaitLogin(ctx context.Context) error {
+	return scraper.WaitLogin(ctx, loginSite)
+}
+
+// Order 注文履歴の1注文分の情報
+type Order struct {
+	ID      string   `json:"id"`      // 注文番号 (デジタル注文は D01- で始まる)
+	Date    string   `json:"date"`    // 注文日
+	Total   string   `json:"total"`   // 合計
+	Items   []string `json:"items"`   // 商品名
+	Sellers []string `json:"sellers"` // マーケットプレイスの出品者名
+}
+
+// Digital Kindle本などのデジタル注文か
+func (o *Order) Digital() bool {
+	return len(o.ID) > 0 && o.ID[0] == 'D'
+}
+
+// inPeriod 注文日が期間内か
+func (o *Order) inPeriod(start, end time.Time) bool {
+	date, err := time.Parse("2006-01-02", receipt.NormalizeDate(o.Date))
+	if err != nil {
+		return false
+	}
+	return !date.Before(start) && date.Before(end)
+}
+
+// GetYearOrders 注文履歴を1ページずつ送って1年分の注文を取得する
+func GetYearOrders(ctx context.Context, year int) ([]*Order, error) {
+	return collectPages(year, func(startIndex int) ([]*Order, error) {
+		return GetOrders(ctx, year, startIndex)
+	})
+}
+
+// collectPages 注文履歴のページを順に読んで注文をまとめる
+// 注文が表示されなくなるか、新しい注文がないページ (前のページと同じ内容など) になったら終わりにする
+func collectPages(year int, getPage func(startIndex int) ([]*Order, error)) ([]*Order, error) {
+	orders := []*Order{}
+	seen := map[string]bool{}
+	for page := 0; page < maxOrderPages; page++ {
+		found, err := getPage(page * ordersPerPage)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, o := range found {
+			if seen[o.ID] {
+				continue
+			}
+			seen[o.ID] = true
+			orders = append(orders, o)
+			added++
+		}
+		if added == 0 {
+			return orders, nil
+		}
+	}
+	return nil, fmt.Errorf("%d年の注文履歴が%dページを超えたため取得を中止しました (ページ送りが効いていない可能性があります)", year, maxOrderPages)
+}
+
+// ordersScript 注文履歴ページの注文ごとのカードから注文番号・注文日・合計・商品名・出品者を取得するJavaScript
+// クラス名は頻繁に変わるため、注文番号などはカードのテキストから正規表現で取り出す
+const ordersScript = `
+	Array.from(document.querySelectorAll('.order-card, .js-order-card')).map(card => {
+		const text = card.innerText;
+		const match = (re) => (text.match(re) || [])[1] || '';
+		const unique = (values) => Array.from(new Set(values.map(v => v.trim()).filter(v => v !== '')));
+		return {
+			id: match(/([0-9D]\d{2}-\d{7}-\d{7})/),
+			date: match(/注文日\s*(\d{4}年\d{1,2}月\d{1,2}日)/),
+			total: match(/合計\s*([￥¥]\s*[\d,]+)/),
+			items: unique(Array.from(card.querySelectorAll('.yohtmlc-product-title, .yohtmlc-item .a-link-normal')).map(e => e.innerText)),
+			sellers: unique(Array.from(card.querySelectorAll('.yohtmlc-item .a-size-small, .yohtmlc-item .a-color-secondary'))
+				.map(e => (e.innerText.match(/販売:\s*(.+)/) || [])[1] || '')),
+		};
+	}).filter(order => order.id !== '');
+`
+
+// GetOrders 注文履歴ページから注文を取得する
+// year: 注文年
+// startIndex: 何件目から表示するか (0始まり)
+func GetOrders(ctx context.Context, year, startIndex int) ([]*Order, error) {
+	orderHistoryURL := fmt.Sprintf("%s/gp/css/order-history?orderFilter=year-%d&startIndex=%d", BaseURL, year, startIndex)
+
+	var orders []*Order
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(orderHistoryURL),
+		chromedp.Evaluate(ordersScript, &orders),
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	return orders, nil
+}
+
+// receiptURL Amazonが発行する領収書／購入明細書の印刷用ページのURL
+func receiptURL(o *Order) string {
+	if o.Digital() {
+		return fmt.Sprintf("%s/gp/digital/your-account/order-summary.html?orderID=%s&print=1", BaseURL, o.ID)
+	}
+	return fmt.Sprintf("%s/gp/css/summary/print.html?orderID=%s", BaseURL, o.ID)
+}
+
+// invoiceListURL 出品者がアップロードした請求書の一覧 (「領収書等」のポップオーバー) のURL
+func invoiceListURL(o *Order) string {
+	return fmt.Sprintf("%s/gp/shared-cs/ajax/invoice/invoice.html?orderId=%s", BaseURL, o.ID)
+}
+
+// DownloadReceipt 注文の領収書を印刷してPDFで保存し、出品者の請求書があればそれも保存する
+func DownloadReceipt(ctx context.Context, o *Order, outputDir string) (*receipt.Receipt, error) {
+	r := &receipt.Receipt{
+		Provider: Provider,
+		ID:       o.ID,
+		URL:      receiptURL(o),
+		Vendor:   Vendor,
+		Date:     receipt.NormalizeDate(o.Date),
+		Total:    receipt.ParseAmount(o.Total),
+		Items:    o.Items,
+	}
+
+	// 全ての商品を同じ出品者が販売している場合はその出品者を発行者にする
+	if len(o.Sellers) == 1 && o.Sellers[0] != "Amazon.co.jp" {
+		r.Vendor = o.Sellers[0]
+	}
+
+	var pdfBuf []byte
+	var receiptText string
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(r.URL),
+		chromedp.WaitReady(`body`, chromedp.ByQuery),
+		chromedp.Text(`body`, &receiptText, chromedp.ByQuery), // 登録番号を探すために領収書のテキストを取得
+		browser.PrintToPDF(&pdfBuf, nil),
+	); err != nil {
+		return nil, fmt.Errorf("failed to download receipt: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s.pdf", r.ID)
+	pdfPath, err := receipt.WritePDF(outputDir, fileName, pdfBuf)
+	if err != nil {
+		return nil, err
+	}
+	r.PDFFile = fileName
+
+	// 適格請求書発行事業者の登録番号を領収書から探す
+	if number, err := invoice.Extract(receiptText); err == nil {
+		r.RegistrationNumber = number
+	} else {
+		fmt.Println(color.YellowString("!"), "領収書に登録番号が見つかりません:", r.ID)
+	}
+
+	// マーケットプレイスの出品者がアップロードした請求書をダウンロード
+	invoices, err := downloadSellerInvoices(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	for i, pdf := range invoices {
+		invoiceFileName := fmt.Sprintf("%s_invoice%d.pdf", r.ID, i+1)
+		if _, err := receipt.WritePDF(outputDir, invoiceFileName, pdf); err != nil {
+			return nil, err
+		}
+		r.Attachments = append(r.Attachments, invoiceFileName)
+	}
+
+	// 注文情報をPDFと並べて保存
+	if _, err := receipt.WriteSidecar(pdfPath, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// sellerInvoicesScript 請求書の一覧のリンクからPDFをログイン中のセッションで取得し、base64で返すJavaScript
+// エラーページやログインページを請求書として保存しないように、失敗した応答とPDF以外の応答は例外にする
+const sellerInvoicesScript = `
+	(async () => {
+		const links = Array.from(document.querySelectorAll('a[href*="/documents/download/"]')).map(a => a.href);
+		const pdfs = [];
+		for (const link of Array.from(new Set(links))) {
+			const res = await fetch(link, { credentials: 'include' });
+			if (!res.ok) {
+				throw new Error(res.status + ' ' + res.statusText + ': ' + link);
+			}
+			const contentType = res.headers.get('Content-Type') || '';
+			if (!/^application\/(pdf|octet-stream)/i.test(contentType)) {
+				throw new Error('PDFではありません (' + contentType + '): ' + link);
+			}
+			const buf = new Uint8Array(await res.arrayBuffer());
+			let binary = '';
+			for (let i = 0; i < buf.length; i++) {
+				binary += String.fromCharCode(buf[i]);
+			}
+			pdfs.push(btoa(binary));
+		}
+		return pdfs;
+	})()
+`
+
+// downloadSellerInvoices 出品者の請求書のPDFをログイン中のセッションで取得する
+func downloadSellerInvoices(ctx context.Context, o *Order) ([][]byte, error) {
+	var encoded []string
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(invoiceListURL(o)),
+		chromedp.WaitReady(`body`, chromedp.ByQuery),
+		chromedp.Evaluate(sellerInvoicesScript, &encoded, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+	); err != nil {
+		return nil, fmt.Errorf("出品者の請求書をダウンロードできませんでした %s: %w", o.ID, err)
+	}
+
+	return decodePDFs(encoded)
+}
+
+// decodePDFs base64のPDFを元に戻し、PDFでないもの (Content-Typeが application/octet-stream のHTMLなど) を拒否する
+func decodePDFs(encoded []string) ([][]byte, error) {
+	pdfs := make([][]byte, 0, len(encoded))
+	for i, e := range encoded {
+		pdf, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+			return nil, fmt.Errorf("出品者の請求書 %d件目がPDFではありません", i+1)
+		}
+		pdfs = append(pdfs, pdf)
+	}
+	return pdfs, nil
+}
+
+// parsePeriod 年月範囲の文字列から期間の開始日と、終了月の翌月1日を作る
+// beforeが空文字の場合はafterの年月のみを取得対象とする
+func parsePeriod(after, before string) (time.Time, time.Time, error) {
+	start, err := time.Parse("200601", after)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("error parsing start date: %v", err)
+	}
+
+	if before == "" {
+		before = after
+	}
+	end, err := time.Parse("200601", before)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("error parsing end date: %v", err)
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end date must be equal to or after start date")
+	}
+
+	return start, end.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,177 @@
+package amazon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/browser/browsertest"
+)
+
+// TestGetYearOrders 保存した注文履歴ページから注文を読み取る
+// テスト用のサーバーはページ送りのパラメータを無視して同じページを返すので、2ページ目で読み終わる
+func TestGetYearOrders(t *testing.T) {
+	ctx := browsertest.NewContext(t)
+	baseURL := BaseURL
+	BaseURL = browsertest.Serve(t, "testdata")
+	t.Cleanup(func() { BaseURL = baseURL })
+
+	got, err := GetYearOrders(ctx, 2024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Order{
+		{ID: "503-1234567-1234567", Date: "2024年1月5日", Total: "￥3,980", Items: []string{"USBケーブル 2本セット", "USB充電器"}, Sellers: []string{"Amazon.co.jp"}},
+		{ID: "249-7654321-7654321", Date: "2024年2月10日", Total: "￥1,200", Items: []string{"ノート A5 5冊"}, Sellers: []string{"文具ショップ"}},
+		{ID: "D01-1111111-2222222", Date: "2024年3月1日", Total: "￥1,320", Items: []string{"Kindle版 技術書"}, Sellers: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, o := range got {
+			t.Logf("%+v", o)
+		}
+		t.Error("orders do not match")
+	}
+}
+
+func TestCollectPages(t *testing.T) {
+	order := func(n int) *Order { return &Order{ID: fmt.Sprintf("503-%07d-0000000", n)} }
+
+	for _, tt := range []struct {
+		name  string
+		pages [][]*Order
+		want  int
+	}{
+		{"empty", nil, 0},
+		{"until empty page", [][]*Order{{order(1), order(2)}, {order(3)}}, 3},
+		{"repeated page", [][]*Order{{order(1), order(2)}, {order(1), order(2)}, {order(3)}}, 2},
+		{"overlapping page", [][]*Order{{order(1), order(2)}, {order(2), order(3)}}, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := 0
+			got, err := collectPages(2024, func(startIndex int) ([]*Order, error) {
+				if startIndex != requested*ordersPerPage {
+					t.Errorf("startIndex = %d", startIndex)
+				}
+				requested++
+				if requested > len(tt.pages) {
+					return nil, nil
+				}
+				return tt.pages[requested-1], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("len = %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	// 毎回新しい注文が返ってくる場合は上限のページ数で止める
+	requested := 0
+	_, err := collectPages(2024, func(startIndex int) ([]*Order, error) {
+		requested++
+		return []*Order{order(requested)}, nil
+	})
+	if err == nil || requested != maxOrderPages {
+		t.Errorf("err = %v, requested = %d", err, requested)
+	}
+}
+
+// TestDownloadReceipt 保存した領収書ページと「領収書等」の一覧から、領収書のPDFとメタデータ・出品者の請求書を保存する
+func TestDownloadReceipt(t *testing.T) {
+	ctx := browsertest.NewContext(t)
+	baseURL := BaseURL
+	BaseURL = browsertest.Serve(t, "testdata")
+	t.Cleanup(func() { BaseURL = baseURL })
+
+	outputDir := t.TempDir()
+	o := &Order{ID: "249-7654321-7654321", Date: "2024年2月10日", Total: "￥1,200", Items: []string{"ノート A5 5冊"}, Sellers: []string{"文具ショップ"}}
+	r, err := DownloadReceipt(ctx, o, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Vendor != "文具ショップ" || r.Date != "2024-02-10" || r.Total != 1200 || r.RegistrationNumber != "T9234567890123" {
+		t.Errorf("receipt = %+v", r)
+	}
+	if r.PDFFile != "249-7654321-7654321.pdf" {
+		t.Errorf("PDFFile = %q", r.PDFFile)
+	}
+	// 同じ請求書へのリンクが2つあっても1回だけ保存する
+	if strings.Join(r.Attachments, ",") != "249-7654321-7654321_invoice1.pdf" {
+		t.Fatalf("Attachments = %q", r.Attachments)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "documents", "download", "inv-0001", "invoice.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(outputDir, r.Attachments[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("seller invoice = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "249-7654321-7654321.json")); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDownloadSellerInvoicesRejected エラーの応答やPDFでない応答を請求書として保存しない
+func TestDownloadSellerInvoicesRejected(t *testing.T) {
+	ctx := browsertest.NewContext(t)
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"not found": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"sign-in page": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<form name="signIn"><input id="ap_email"></form>`))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/gp/shared-cs/ajax/invoice/invoice.html", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte(`<a href="/documents/download/inv-0001/invoice.pdf">請求書 1</a>`))
+			})
+			mux.HandleFunc("/documents/download/", handler)
+			srv := httptest.NewServer(mux)
+			t.Cleanup(srv.Close)
+
+			baseURL := BaseURL
+			BaseURL = srv.URL
+			t.Cleanup(func() { BaseURL = baseURL })
+
+			if pdfs, err := downloadSellerInvoices(ctx, &Order{ID: "249-7654321-7654321"}); err == nil {
+				t.Errorf("want error, got %d files", len(pdfs))
+			}
+		})
+	}
+}
+
+func TestDecodePDFs(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4\n"))
+	html := base64.StdEncoding.EncodeToString([]byte("<!DOCTYPE html>"))
+
+	got, err := decodePDFs([]string{pdf})
+	if err != nil || len(got) != 1 || string(got[0]) != "%PDF-1.4\n" {
+		t.Errorf("decodePDFs = %q, %v", got, err)
+	}
+	if _, err := decodePDFs([]string{pdf, html}); err == nil {
+		t.Error("html: want error")
+	}
+	if _, err := decodePDFs([]string{"!"}); err == nil {
+		t.Error("invalid base64: want error")
+	}
+}
@@ -2,254 +2,250 @@ package bookwalker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"regexp"
-	"syscall"
-	"time"
-
-	"github.com/chromedp/cdproto/page"
-	"github.com/chromedp/chromedp"
-	"github.com/schollz/progressbar/v3"
-	"golang.org/x/term"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/fatih/color"
 )
 
-// Run メイン処理
-func Run(ctx context.Context, after, before, outputDir string) error {
+// Provider 領収書の取得元の名前
+const Provider = "bookwalker"
 
-	// 取得対象の年月範囲を生成
-	targetDate, err := generateYearMonths(after, before)
-	if err != nil {
-		return err
-	}
+// Vendor 領収書の発行者
+const Vendor = "株式会社ブックウォーカー"
 
-	// chromedpの設定
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", false),
-	)
-	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
-	defer cancel()
-
-	ctx, cancel = chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	// BOOKWALKERログイン
-	fmt.Println("Chromeを自動操作してBOOKWALKERにログインします。")
-	fmt.Println("ログイン情報を入力してください。")
-	fmt.Printf("メールアドレス📩: ")
-	email := ""
-	// メールアドレスを入力させる
-	fmt.Scanln(&email)
-	fmt.Printf("パスワード🔑: ")
-	password, err := ReadPassword()
-	if err != nil {
-		return err
-	}
-	fmt.Println()
+// ストアの地域
+const (
+	RegionJP     = "jp"     // 日本のストア (bookwalker.jp)
+	RegionGlobal = "global" // 英語のグローバルストア (global.bookwalker.jp)
+)
 
-	// Chromeでのログイン処理
-	if err := Login(ctx, email, string(password)); err != nil {
-		return err
-	}
+// Regions 指定できるストアの地域
+func Regions() []string {
+	return []string{RegionJP, RegionGlobal}
+}
 
-	// reCAPTCHAが入ることがあるのでそれを待機する
-	fmt.Println("ログインボタンを押してください。(reCAPTCHAが表示されたら手動で操作して完了してください)")
-	if err := WaitLogin(ctx); err != nil {
-		return err
-	}
+// store 地域ごとのストアの違い
+type store struct {
+	region      string
+	id          string // ログイン情報とセッションを保存する名前
+	displayName string
+	vendor      string
+	currency    string // 金額に通貨記号がない場合の通貨コード
+	idPrefix    string // 領収書IDの前に付ける文字列 (日本のストアの領収書とファイル名が重ならないようにする)
+
+	loginURL      string
+	emailField    string
+	passwordField string
+	success       string
+	loginError    string
+	historyURL    string
+
+	receiptWait     string // 領収書ページで表示を待つ要素
+	addresseeField  string // 宛名の入力欄 (空の場合は宛名を入力できない)
+	addresseeSubmit string // 宛名を反映するボタン
+
+	lang         string // 購入履歴の値の要素のクラス (ja_val, en_val)
+	subscription string // 定期購入の行を判定するJavaScriptの正規表現
+	coin         string // コイン購入の行を判定するJavaScriptの正規表現
+}
 
-	// 領収書ページを開いて各領収書のURLを取得
-	fmt.Println("領収書のURLを取得します")
-	receiptURLs := []string{}
-	gerURLProgressBar := progressbar.Default(int64(len(targetDate)))
-	for _, date := range targetDate {
-
-		// 対象月の領収書を1ページ目から取得する
-		page := 1
-		for {
-			urls, err := GetReceiptURLs(ctx, date, page)
-			if err != nil {
-				return err
-			}
-
-			if len(urls) == 0 {
-				// そのページが存在しなくてもURLにはアクセスできるが、領収書が存在しないページになる
-				// 取得できたURLが0件になった場合、その月の領収書URLは全て取得しているはずなのでループを抜け次の月へ進める
-				break
-			}
-
-			receiptURLs = append(receiptURLs, urls...)
-			page++
-		}
+var stores = map[string]store{
+	RegionJP: {
+		region:      RegionJP,
+		id:          Provider,
+		displayName: "BOOKWALKER",
+		vendor:      Vendor,
+
+		loginURL:      "https://member.bookwalker.jp/app/03/login", // BOOKWALKERのログインページ
+		emailField:    `#mailAddress`,
+		passwordField: `#password`,
+		success:       `#lt_payment_history`, // 決済履歴ボタン
+		// メールアドレスかパスワードが違う場合にフォームの上に表示されるエラーメッセージ
+		loginError: `.errorMessage, .error-message, .m-error, [role="alert"]`,
+		historyURL: "https://member.bookwalker.jp/app/03/my/paymenthistory/{{.YearMonth}}?page={{.Page}}",
+
+		receiptWait:     `#main1`, // 領収書の要素
+		addresseeField:  `input[name="atena"]`,
+		addresseeSubmit: `#atena_submit, button[name="atena_submit"]`,
+
+		lang:         "ja_val",
+		subscription: `/読み放題|月額/`,
+		coin:         `/コイン(チャージ|購入)|BOOK☆WALKERコイン/`,
+	},
+	// グローバルストアのURLとセレクタは実際のページで確認できていないため、合わない場合は
+	// 設定ディレクトリの sites/bookwalker-global.yaml で置き換える (SiteFor を参照)
+	RegionGlobal: {
+		region:      RegionGlobal,
+		id:          Provider + "-" + RegionGlobal, // 日本のストアとはアカウントが別
+		displayName: "BOOK☆WALKER Global",
+		vendor:      "BOOK WALKER Co., Ltd.",
+		currency:    "USD",
+		idPrefix:    RegionGlobal + "-",
+
+		loginURL:      "https://global.bookwalker.jp/login/",
+		emailField:    `#mailAddress, input[name="mailAddress"], input[type="email"]`,
+		passwordField: `#password, input[name="password"]`,
+		success:       `a[href*="paymenthistory"]`, // Payment History のリンク
+		loginError:    `.errorMessage, .error-message, .m-error, [role="alert"]`,
+		historyURL:    "https://global.bookwalker.jp/my/paymenthistory/{{.YearMonth}}?page={{.Page}}",
+
+		// 領収書ページの構成が分からないので、読み込みが終わったページをそのまま印刷する
+		// 宛名の入力欄も分からないので、宛名を指定したプロファイルではエラーにする
+		receiptWait: `body`,
+
+		lang:         "en_val",
+		subscription: `/unlimited|monthly|subscription/i`,
+		coin:         `/coins? (charge|purchase)|BOOK☆WALKER coin/i`,
+	},
+}
 
-		gerURLProgressBar.Add(1)
-	}
+// Site 日本のBOOKWALKERのサイト定義
+var Site = newSite(stores[RegionJP])
 
-	fmt.Println("領収書のURLを取得しました 件数:", len(receiptURLs))
+// GlobalSite グローバルストアのBOOK☆WALKERのサイト定義
+var GlobalSite = newSite(stores[RegionGlobal])
 
-	// 領収書をダウンロード
-	fmt.Println("領収書をダウンロードします")
-	downloadProgressBar := progressbar.Default(int64(len(receiptURLs)))
-	for _, url := range receiptURLs {
-		if err := DownloadReceipt(ctx, url, outputDir); err != nil {
-			return err
+// SiteFor 地域のストアのサイト定義 (空の場合は日本のストア)
+// 設定ディレクトリに sites/{bookwalker または bookwalker-global}.yaml があれば、組み込みの定義の代わりに使う
+func SiteFor(region string) (*scraper.Site, error) {
+	var site *scraper.Site
+	switch strings.ToLower(region) {
+	case "", RegionJP:
+		site = Site
+	case RegionGlobal:
+		site = GlobalSite
+	default:
+		return nil, fmt.Errorf("BOOKWALKERの地域が不正です: %s (指定できる地域: %s)", region, strings.Join(Regions(), ", "))
+	}
+
+	sitesDir, err := scraper.SitesDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(sitesDir, site.ID+".yaml")
+	if _, err := os.Stat(path); err != nil {
+		if site == GlobalSite {
+			fmt.Println(color.YellowString("!"), "グローバルストアへの対応は試験的です。取得できない場合は", path, "にサイト定義を書いてください。")
 		}
-		downloadProgressBar.Add(1)
+		return site, nil
 	}
+	fmt.Println("サイト定義を読み込みます:", path)
+	return scraper.LoadSite(path)
+}
 
-	return nil
+// rowsScript 購入履歴ページの行を読み取るJavaScript
+// 領収書のページがない行 (コインのチャージ、ポイントだけの購入など) も支払の種類を付けて返す
+const rowsScript = `
+	((lang, subscription, coin) => Array.from(document.querySelectorAll('.PaymentDetails')).map(el => {
+		const text = (selector) => {
+			const e = el.querySelector(selector + ' .' + lang);
+			return e ? e.innerText.trim() : '';
+		};
+		const total = text('.payment_total');
+		const price = parseFloat(total.replace(/[^\d.]/g, '')) || 0;
+		// 書籍のリンクと区別するため領収書ページへのリンクだけを探す
+		const receiptLink = el.querySelector('a[href*="purchaseDetail"]');
+		// 領収書リンク以外の行を書籍タイトルとして扱う
+		const titles = text('.purchase_books').split('\n')
+			.map(t => t.trim())
+			.filter(t => t !== '' && (!receiptLink || t !== receiptLink.innerText.trim()));
+		let type = 'purchase';
+		if (subscription.test(el.innerText)) {
+			type = 'subscription';
+		} else if (coin.test(titles.join('\n'))) {
+			type = 'coin';
+		} else if (price === 0) {
+			type = 'point';
+		}
+		return {
+			url: receiptLink ? receiptLink.href : '',
+			date: text('.payment_date'),
+			total: total,
+			paymentMethod: text('.payment_method'),
+			coin: text('.payment_coin'),
+			point: text('.payment_point'),
+			type: type,
+			items: titles,
+		};
+	}))(%s, %s, %s)
+`
+
+func newSite(st store) *scraper.Site {
+	site := &scraper.Site{
+		Name:        Provider,
+		DisplayName: st.displayName,
+		Vendor:      st.vendor,
+		ID:          st.id,
+		Region:      st.region,
+		Currency:    st.currency,
+	}
+
+	site.Login.URL = st.loginURL
+	site.Login.EmailField = st.emailField
+	site.Login.PasswordField = st.passwordField
+	site.Login.Success = st.success
+	site.Login.Error = st.loginError
+
+	site.History.URL = st.historyURL
+	site.History.Period = "month"
+	site.History.FirstPage = 1
+	site.History.Rows = fmt.Sprintf(rowsScript, jsString(st.lang), st.subscription, st.coin)
+
+	site.Receipt.Wait = st.receiptWait
+	site.Receipt.Addressee.Field = st.addresseeField
+	site.Receipt.Addressee.Submit = st.addresseeSubmit
+	// 印刷するときはヘッダー・フッターと宛名の入力フォームを隠す
+	site.Receipt.CSS = `header, footer, .header, .footer, .no-print, form:has(input[name="atena"]) { display: none !important; }`
+	// URLの https://user.bookwalker.jp/app/purchaseDetail/{id}/ja (グローバルストアは /en) から{id}部分を取り出す正規表現
+	site.Receipt.ID = `/purchaseDetail/(\d+)`
+	site.Receipt.IDPrefix = st.idPrefix
+
+	if err := site.Compile(); err != nil {
+		panic(err)
+	}
+	return site
 }
 
-// ReadPassword パスワード入力モードで入力させる
-func ReadPassword() ([]byte, error) {
-	// Ctrl+Cのシグナルをキャプチャする
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
-	defer signal.Stop(signalChan)
+// jsString 文字列をJavaScriptの文字列リテラルにする
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
 
-	// 現在のターミナルの状態をコピーしておく
-	currentState, err := term.GetState(int(syscall.Stdin))
+// Run メイン処理
+// region: ストアの地域 (jp, global。空の場合は jp)
+func Run(ctx context.Context, region string, opts *scraper.Options, after, before, outputDir string) error {
+	site, err := SiteFor(region)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	go func() {
-		<-signalChan
-		// Ctrl+Cを受信後、ターミナルの状態を先ほどのコピーを用いて元に戻す
-		term.Restore(int(syscall.Stdin), currentState)
-		os.Exit(1)
-	}()
-
-	return term.ReadPassword(syscall.Stdin)
+	return scraper.Run(ctx, site, opts, after, before, outputDir)
 }
 
 // Login Chromeを自動操作してBOOKWALKERにログインする
 func Login(ctx context.Context, email string, password string) error {
-	chromedp.Run(ctx,
-		chromedp.Navigate("https://member.bookwalker.jp/app/03/login"), // BOOKWALKERのログインページに遷移
-		chromedp.WaitVisible(`#mailAddress`, chromedp.ByQuery),         // メールアドレスの入力欄が表示されるまで待機
-		chromedp.SendKeys(`#mailAddress`, email, chromedp.ByQuery),     // メールアドレスを入力
-		chromedp.SendKeys(`#password`, password, chromedp.ByQuery),     // パスワードを入力
-	)
-	return nil
+	return scraper.Login(ctx, Site, email, password)
 }
 
 // WaitLogin ログイン完了まで待機する
 func WaitLogin(ctx context.Context) error {
-	chromedp.Run(ctx,
-		chromedp.WaitVisible((`#lt_payment_history`), chromedp.ByID), // 決済履歴ボタンが表示されるまで待機
-	)
-	return nil
+	return scraper.WaitLogin(ctx, Site)
 }
 
-// GetReceiptURLs BOOKWALKERの領収書のURLを取得する
+// GetReceipts BOOKWALKERの領収書のURLと購入情報を取得する
 // date: YYYYMM
 // page: ページ(1始まり)
-func GetReceiptURLs(ctx context.Context, date string, page int) ([]string, error) {
-	// 決済履歴ページのURL
-	paymentHistoryURL := fmt.Sprintf("https://member.bookwalker.jp/app/03/my/paymenthistory/%s?page=%d", date, page)
-	// fmt.Println("決済履歴ページのURL : " + paymentHistoryURL)
-
-	// 決済履歴ページから領収書URLを取得
-	var receiptURLs []string
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(paymentHistoryURL),
-		chromedp.Evaluate(`
-			Array.from(document.querySelectorAll('.PaymentDetails')).map(el => {
-				const price = parseInt(el.querySelector('.payment_total .ja_val').innerText.replace(/,/g, ''), 10);
-				const receiptLink = el.querySelector('.purchase_books .ja_val a');
-				if (price > 0 && receiptLink) {
-					return receiptLink.href;
-				}
-				return null;
-			}).filter(url => url !== null);
-		`, &receiptURLs),
-	); err != nil {
-		return nil, fmt.Errorf("failed to fetch receipt URLs: %w", err)
-	}
-	return receiptURLs, nil
-}
-
-// DownloadReceipt 領収書PDFページを開き、保存する
-func DownloadReceipt(ctx context.Context, receiptURL string, outputDir string) error {
-	var pdfBuf []byte
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(receiptURL),
-		chromedp.WaitVisible(`#main1`, chromedp.ByQuery), // 領収書の要素が表示されるまで待機
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			printParams := page.PrintToPDF()
-			printParams.PrintBackground = true
-			printParams.PaperWidth = 8.27 // A4 paper size
-			printParams.PaperHeight = 11.69
-
-			pdf, _, err := printParams.Do(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to generate PDF: %w", err)
-			}
-			pdfBuf = pdf
-			return nil
-		}),
-	); err != nil {
-		return fmt.Errorf("failed to download receipt: %w", err)
-	}
-
-	// URLの https://user.bookwalker.jp/app/purchaseDetail/{id}/ja　から{id}部分を取り出す正規表現
-	// 領収書のIDをファイル名にする
-	receiptID := regexp.MustCompile(`https://user.bookwalker.jp/app/purchaseDetail/(\d+)/ja`).FindStringSubmatch(receiptURL)[1]
-
-	// ダウンロードしたPDFを保存
-	fileName := fmt.Sprintf("%s.pdf", receiptID)
-	pdfPath := filepath.Join(outputDir, fileName)
-
-	// ディレクトリがなければ作成する
-	// ディレクトリの有無をチェック
-	if _, err := os.Stat(filepath.Dir(pdfPath)); os.IsNotExist(err) {
-		// ディレクトリがなければ作成
-		if err := os.MkdirAll(outputDir, 0o755); err != nil {
-			return fmt.Errorf("出力先ディレクトリの作成に失敗しました: %w", err)
-		}
-	}
-
-	if err := os.WriteFile(pdfPath, pdfBuf, 0o644); err != nil {
-		return fmt.Errorf("PDFの保存に失敗しました : %w", err)
-	}
-
-	return nil
+func GetReceipts(ctx context.Context, date string, page int) ([]*receipt.Receipt, error) {
+	return scraper.GetReceipts(ctx, Site, date, page)
 }
 
-// generateYearMonths 年月範囲の文字列のスライスを作る
-func generateYearMonths(after, before string) ([]string, error) {
-	// after: "202301"
-	// before: "202312"
-	// 例:[ "202301", "202302", "202303", ... , "202312"]
-	// beforeが空文字の場合はafterの年月のみを取得対象とする
-
-	startDate, err := time.Parse("200601", after)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing start date: %v", err)
-	}
-
-	if before == "" {
-		return []string{startDate.Format("200601")}, nil
-	}
-
-	endDate, err := time.Parse("200601", before)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing end date: %v", err)
-	}
-
-	if endDate.Before(startDate) {
-		return nil, fmt.Errorf("end date must be equal to or after start date")
-	}
-
-	yearMonths := []string{}
-	current := startDate
-	for !current.After(endDate) {
-		yearMonths = append(yearMonths, current.Format("200601"))
-		current = current.AddDate(0, 1, 0)
-	}
-
-	return yearMonths, nil
+// DownloadReceipt 領収書PDFページを開き、PDFとメタデータを保存する
+func DownloadReceipt(ctx context.Context, profile *scraper.Profile, r *receipt.Receipt, outputDir string) error {
+	return scraper.DownloadReceipt(ctx, Site, profile, r, outputDir)
 }
@@ -0,0 +1,84 @@
+package bookwalker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/browser/browsertest"
+	"github.com/JINZO631/freeedom/pkg/scraper"
+	"github.com/chromedp/chromedp"
+)
+
+// TestRows 保存した決済履歴ページで購入履歴の行を読み取るJavaScriptを評価する
+func TestRows(t *testing.T) {
+	ctx := browsertest.NewContext(t)
+	baseURL := browsertest.Serve(t, "testdata")
+
+	for _, tt := range []struct {
+		site *scraper.Site
+		page string
+	}{
+		{Site, "paymenthistory_ja"},
+		{GlobalSite, "paymenthistory_en"},
+	} {
+		t.Run(tt.page, func(t *testing.T) {
+			var got []map[string]any
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(baseURL+"/"+tt.page+".html"),
+				chromedp.Evaluate(tt.site.History.Rows, &got),
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			var want []map[string]any
+			b, err := os.ReadFile(filepath.Join("testdata", tt.page+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(b, &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				t.Errorf("rows = %s", gotJSON)
+			}
+		})
+	}
+}
+
+func TestReceiptID(t *testing.T) {
+	for _, tt := range []struct {
+		site *scraper.Site
+		url  string
+		want string
+	}{
+		{Site, "https://user.bookwalker.jp/app/purchaseDetail/1001/ja", "1001"},
+		{Site, "https://user.bookwalker.jp/app/purchaseDetail/1001/en", "1001"},
+		{GlobalSite, "https://user.bookwalker.jp/app/purchaseDetail/2001/en", "2001"},
+	} {
+		m := regexp.MustCompile(tt.site.Receipt.ID).FindStringSubmatch(tt.url)
+		if len(m) < 2 || m[1] != tt.want {
+			t.Errorf("%s: %q = %v, want %s", tt.site.ID, tt.url, m, tt.want)
+		}
+	}
+	if GlobalSite.Receipt.IDPrefix == Site.Receipt.IDPrefix {
+		t.Error("the global store must not share receipt IDs with the JP store")
+	}
+}
+
+func TestSiteFor(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for region, want := range map[string]*scraper.Site{"": Site, "jp": Site, "JP": Site, "global": GlobalSite} {
+		got, err := SiteFor(region)
+		if err != nil || got != want {
+			t.Errorf("SiteFor(%q) = %v, %v", region, got.ID, err)
+		}
+	}
+	if _, err := SiteFor("us"); err == nil {
+		t.Error(`SiteFor("us"): want error`)
+	}
+}
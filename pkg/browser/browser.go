@@ -0,0 +1,63 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// NewContext 画面を表示した状態で自動操作するChromeのコンテキストを作る
+// ログインやreCAPTCHAは利用者が手動で操作することがあるためheadlessにはしない
+func NewContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", false),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	chromedpCtx, cancelCtx := chromedp.NewContext(allocCtx)
+
+	return chromedpCtx, func() {
+		cancelCtx()
+		cancelAlloc()
+	}
+}
+
+// PrintToPDF 表示中のページを印刷設定に従ってPDFにする
+// opts が nil の場合は余白なしのA4サイズにする
+func PrintToPDF(pdfBuf *[]byte, opts *PrintOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if opts == nil {
+			opts = &PrintOptions{}
+		}
+		size, err := opts.paperSize()
+		if err != nil {
+			return err
+		}
+		margin, err := parseMargin(opts.Margin)
+		if err != nil {
+			return err
+		}
+		if err := InjectCSS(opts.CSS).Do(ctx); err != nil {
+			return err
+		}
+
+		printParams := page.PrintToPDF()
+		printParams.PrintBackground = true
+		printParams.PaperWidth = size[0]
+		printParams.PaperHeight = size[1]
+		printParams.Landscape = opts.Landscape
+		printParams.MarginTop = margin[0]
+		printParams.MarginRight = margin[1]
+		printParams.MarginBottom = margin[2]
+		printParams.MarginLeft = margin[3]
+		printParams.Scale = opts.Scale
+
+		pdf, _, err := printParams.Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate PDF: %w", err)
+		}
+		*pdfBuf = pdf
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+// Package browsertest 保存したHTMLをヘッドレスのChromeで開いて確かめるテストの補助
+package browsertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ChromeEnv テストで使うChromeのパスを指定する環境変数 (省略時は PATH から探す)
+const ChromeEnv = "FREEEDOM_TEST_CHROME"
+
+// NewContext ヘッドレスのChromeのコンテキストを作る
+// Chromeを起動できない環境ではテストをスキップする
+func NewContext(t testing.TB) context.Context {
+	t.Helper()
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.NoSandbox)
+	if path := os.Getenv(ChromeEnv); path != "" {
+		opts = append(opts, chromedp.ExecPath(path))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+	t.Cleanup(func() {
+		cancelCtx()
+		cancelAlloc()
+	})
+
+	if err := chromedp.Run(ctx); err != nil {
+		t.Skipf("Chromeを起動できないためスキップします (%s でパスを指定できます): %v", ChromeEnv, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// Serve ディレクトリのファイルを返すローカルのHTTPサーバーを起動してURLを返す
+func Serve(t testing.TB, dir string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
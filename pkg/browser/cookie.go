@@ -0,0 +1,62 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// GetCookies ブラウザの全てのCookieを取得する (ログインしたセッションを保存するのに使う)
+func GetCookies(cookies *[]*network.Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		cs, err := storage.GetCookies().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get cookies: %w", err)
+		}
+		*cookies = cs
+		return nil
+	})
+}
+
+// SetCookies 保存しておいたCookieをブラウザに設定する
+// 有効期限が切れたCookieは設定しない
+func SetCookies(cookies []*network.Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		now := time.Now()
+		params := []*network.CookieParam{}
+		for _, c := range cookies {
+			p := &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HTTPOnly,
+				SameSite: c.SameSite,
+				Priority: c.Priority,
+			}
+			if !c.Session {
+				expires := time.Unix(int64(c.Expires), 0)
+				if expires.Before(now) {
+					continue
+				}
+				e := cdp.TimeSinceEpoch(expires)
+				p.Expires = &e
+			}
+			params = append(params, p)
+		}
+		if len(params) == 0 {
+			return nil
+		}
+
+		if err := storage.SetCookies(params).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set cookies: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// downloadTimeout ダウンロードが終わるまで待つ時間
+const downloadTimeout = 2 * time.Minute
+
+// DenyDownloads ページを開いてもファイルをダウンロードしないようにする (ログインのために開く場合など)
+func DenyDownloads() chromedp.Action {
+	return browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorDeny)
+}
+
+// Download URLを開いてダウンロードしたファイルを path に保存する
+// ダウンロードが始まらない・キャンセルされた・時間内に終わらない場合はエラーを返す
+func Download(ctx context.Context, url, path string) error {
+	// 先にブラウザを起動しておく (タイムアウトを付けたコンテキストで起動するとブラウザごと閉じてしまうため)
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+
+	// 同じディレクトリの一時ディレクトリにダウンロードしてから置き換える
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("出力先ディレクトリの作成に失敗しました: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(path), ".download")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	absDir, err := filepath.Abs(tmpDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	// ダウンロードしたファイルは GUID をファイル名にして保存される
+	done := make(chan string, 1)
+	failed := make(chan error, 1)
+	chromedp.ListenTarget(ctx, func(ev any) {
+		e, ok := ev.(*browser.EventDownloadProgress)
+		if !ok {
+			return
+		}
+		switch e.State {
+		case browser.DownloadProgressStateCompleted:
+			select {
+			case done <- e.GUID:
+			default:
+			}
+		case browser.DownloadProgressStateCanceled:
+			select {
+			case failed <- errors.New("ダウンロードがキャンセルされました"):
+			default:
+			}
+		}
+	})
+
+	err = chromedp.Run(ctx,
+		browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+			WithDownloadPath(absDir).
+			WithEventsEnabled(true),
+		chromedp.Navigate(url),
+	)
+	// ダウンロードが始まるとページの読み込みは中断される
+	if err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
+		return fmt.Errorf("ダウンロードできませんでした %s: %w", url, err)
+	}
+
+	select {
+	case guid := <-done:
+		if err := os.Rename(filepath.Join(absDir, guid), path); err != nil {
+			return fmt.Errorf("ダウンロードしたファイルを保存できませんでした: %w", err)
+		}
+		return nil
+	case err := <-failed:
+		return fmt.Errorf("%w: %s", err, url)
+	case <-ctx.Done():
+		return fmt.Errorf("ダウンロードが終わりませんでした %s: %w", url, ctx.Err())
+	}
+}
@@ -0,0 +1,143 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultPaper 用紙サイズのデフォルト
+const DefaultPaper = "A4"
+
+// paperSizes 用紙サイズ (幅, 高さ。単位はインチ)
+var paperSizes = map[string][2]float64{
+	"A3":     {11.69, 16.54},
+	"A4":     {8.27, 11.69},
+	"A5":     {5.83, 8.27},
+	"B4":     {10.12, 14.33}, // JIS
+	"B5":     {7.17, 10.12},  // JIS
+	"LETTER": {8.5, 11},
+	"LEGAL":  {8.5, 14},
+}
+
+// lengthUnits 余白に使える単位 (1単位あたりのインチ)
+var lengthUnits = map[string]float64{
+	"mm": 1 / 25.4,
+	"cm": 1 / 2.54,
+	"in": 1,
+	"pt": 1.0 / 72,
+	"px": 1.0 / 96,
+}
+
+// PrintOptions PDFにするときの印刷設定
+type PrintOptions struct {
+	Paper     string  `yaml:"paper"`     // 用紙サイズ (A3, A4, A5, B4, B5, Letter, Legal。デフォルトはA4)
+	Landscape bool    `yaml:"landscape"` // 横向きにする
+	Margin    string  `yaml:"margin"`    // 余白 (CSSと同じく1〜4個の値。例: 10mm, "10mm 15mm"。単位を省略した場合はmm)
+	Scale     float64 `yaml:"scale"`     // 拡大率 (0.1〜2。デフォルトは1)
+	CSS       string  `yaml:"css"`       // 印刷前にページに追加するCSS (ナビゲーションなどを隠す)
+}
+
+// Validate 印刷設定の値を確認する
+func (o *PrintOptions) Validate() error {
+	if _, err := o.paperSize(); err != nil {
+		return err
+	}
+	if _, err := parseMargin(o.Margin); err != nil {
+		return err
+	}
+	if o.Scale != 0 && (o.Scale < 0.1 || o.Scale > 2) {
+		return fmt.Errorf("拡大率は0.1〜2で指定してください: %v", o.Scale)
+	}
+	return nil
+}
+
+// paperSize 用紙の幅と高さ (インチ)
+func (o *PrintOptions) paperSize() ([2]float64, error) {
+	paper := o.Paper
+	if paper == "" {
+		paper = DefaultPaper
+	}
+	size, ok := paperSizes[strings.ToUpper(paper)]
+	if !ok {
+		names := make([]string, 0, len(paperSizes))
+		for name := range paperSizes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return size, fmt.Errorf("対応していない用紙サイズです: %s (対応: %s)", o.Paper, strings.Join(names, ", "))
+	}
+	return size, nil
+}
+
+// parseMargin CSSの margin と同じ形式の余白を上・右・下・左のインチにする
+func parseMargin(margin string) ([4]float64, error) {
+	var m [4]float64
+	fields := strings.Fields(margin)
+	values := make([]float64, 0, len(fields))
+	for _, f := range fields {
+		v, err := parseLength(f)
+		if err != nil {
+			return m, err
+		}
+		values = append(values, v)
+	}
+
+	switch len(values) {
+	case 0:
+	case 1:
+		m = [4]float64{values[0], values[0], values[0], values[0]}
+	case 2:
+		m = [4]float64{values[0], values[1], values[0], values[1]}
+	case 3:
+		m = [4]float64{values[0], values[1], values[2], values[1]}
+	case 4:
+		m = [4]float64{values[0], values[1], values[2], values[3]}
+	default:
+		return m, fmt.Errorf("余白は1〜4個の値で指定してください: %s", margin)
+	}
+	return m, nil
+}
+
+// parseLength 長さ (例: 10mm, 0.5in) をインチにする
+func parseLength(s string) (float64, error) {
+	num, unit := s, "mm"
+	for u := range lengthUnits {
+		if strings.HasSuffix(s, u) {
+			num, unit = strings.TrimSuffix(s, u), u
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("余白の長さが不正です: %s (例: 10mm, 1cm, 0.5in)", s)
+	}
+	return v * lengthUnits[unit], nil
+}
+
+// InjectCSS 表示中のページにCSSを追加する
+func InjectCSS(css string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if strings.TrimSpace(css) == "" {
+			return nil
+		}
+		b, err := json.Marshal(css)
+		if err != nil {
+			return err
+		}
+		script := fmt.Sprintf(`(() => {
+			const style = document.createElement('style');
+			style.textContent = %s;
+			document.head.appendChild(style);
+		})()`, b)
+		if err := chromedp.Evaluate(script, nil).Do(ctx); err != nil {
+			return fmt.Errorf("failed to inject CSS: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,178 @@
+// Package credential サイトにログインするメールアドレスとパスワードを取得する
+//
+// 次の順に探し、最初に見つかったものを使う。
+//   - 外部コマンドのヘルパー (git credential と同じ key=value の形式でやり取りする)
+//   - 環境変数 FREEEDOM_{サイト名}_EMAIL / FREEEDOM_{サイト名}_PASSWORD
+//   - 暗号化して保存したログイン情報 (設定ディレクトリの credentials/)
+//   - 入力 (端末から実行している場合のみ)
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/prompt"
+	"github.com/fatih/color"
+)
+
+// HelperEnv 外部コマンドのヘルパーを指定する環境変数 (--credential-helper を省略した場合に使う)
+const HelperEnv = "FREEEDOM_CREDENTIAL_HELPER"
+
+// Credentials ログイン情報
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+
+	source string // 取得元 (helper, env, store, prompt)
+}
+
+// Options ログイン情報の取得方法
+type Options struct {
+	Helper  string // 外部コマンドのヘルパー (空の場合は環境変数 FREEEDOM_CREDENTIAL_HELPER)
+	Account string // ログインするアカウント (メールアドレス)。同じサイトの複数のアカウントを使い分ける場合に指定する
+}
+
+// Resolver サイトのログイン情報を取得する
+type Resolver struct {
+	Site    string // サイト名
+	Host    string // ログインページのホスト (ヘルパーに渡す)
+	Account string
+	Helper  string
+}
+
+// New サイトのログイン情報を取得する Resolver を作成する
+func New(site, loginURL string, opts Options) *Resolver {
+	host := ""
+	if u, err := url.Parse(loginURL); err == nil {
+		host = u.Host
+	}
+	helper := opts.Helper
+	if helper == "" {
+		helper = os.Getenv(HelperEnv)
+	}
+	return &Resolver{Site: site, Host: host, Account: opts.Account, Helper: helper}
+}
+
+// Get ログイン情報を取得する
+func (r *Resolver) Get(ctx context.Context) (*Credentials, error) {
+	if r.Helper != "" {
+		c, err := r.helper(ctx, "get", nil)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil && c.Password != "" {
+			c.source = "helper"
+			return c, nil
+		}
+	}
+
+	if c := r.env(); c != nil {
+		c.source = "env"
+		return c, nil
+	}
+
+	c, err := Load(r.Site, r.Account)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		fmt.Println("保存したログイン情報を使います:", c.Email)
+		c.source = "store"
+		return c, nil
+	}
+
+	return r.Prompt()
+}
+
+// Approve ログインに成功したログイン情報をヘルパーに保存させる (入力した場合のみ)
+func (r *Resolver) Approve(ctx context.Context, c *Credentials) error {
+	if r.Helper == "" || c.source != "prompt" {
+		return nil
+	}
+	_, err := r.helper(ctx, "store", c)
+	return err
+}
+
+// Reject ログインに失敗したログイン情報を保存先から削除する (暗号化して保存したログイン情報のみ)
+// ヘルパーのパスワードマネージャーなど、外部に保存しているログイン情報は削除しない
+func (r *Resolver) Reject(c *Credentials) error {
+	if c.source != "store" {
+		return nil
+	}
+	return Remove(r.Site, r.Account)
+}
+
+// EnvName サイトのログイン情報を渡す環境変数の名前 (例: FREEEDOM_BOOKWALKER_PASSWORD)
+func EnvName(site, key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, site)
+	return "FREEEDOM_" + name + "_" + key
+}
+
+// env 環境変数からログイン情報を取得する (パスワードがなければ nil)
+// メールアドレスの環境変数がなければ --credential-account を使う
+func (r *Resolver) env() *Credentials {
+	password := os.Getenv(EnvName(r.Site, "PASSWORD"))
+	if password == "" {
+		return nil
+	}
+	email := os.Getenv(EnvName(r.Site, "EMAIL"))
+	if email == "" {
+		email = r.Account
+	}
+	return &Credentials{Email: email, Password: password}
+}
+
+// Prompt ログイン情報を入力させ、暗号化して保存するか確認する
+// 端末から実行していない場合はエラーを返す
+func (r *Resolver) Prompt() (*Credentials, error) {
+	if !prompt.IsTerminal() {
+		return nil, fmt.Errorf("%s のログイン情報が見つかりません。--credential-helper か環境変数 %s / %s を指定してください",
+			r.Site, EnvName(r.Site, "EMAIL"), EnvName(r.Site, "PASSWORD"))
+	}
+
+	c := &Credentials{Email: r.Account, source: "prompt"}
+	if c.Email == "" {
+		email, password, err := prompt.ReadCredentials()
+		if err != nil {
+			return nil, err
+		}
+		c.Email, c.Password = email, password
+	} else {
+		fmt.Printf("%s のパスワード🔑: ", c.Email)
+		password, err := prompt.ReadPassword()
+		fmt.Println()
+		if err != nil {
+			return nil, err
+		}
+		c.Password = string(password)
+	}
+	if c.Email == "" || c.Password == "" {
+		return nil, errors.New("メールアドレスとパスワードを入力してください")
+	}
+
+	// ヘルパーを使う場合はログインに成功してからヘルパーに保存させる
+	if r.Helper != "" {
+		return c, nil
+	}
+	answer := prompt.ReadLine("ログイン情報を暗号化して保存しますか? (y/N)")
+	if strings.EqualFold(answer, "y") {
+		if err := Save(r.Site, r.Account, c); err != nil {
+			fmt.Println(color.YellowString("!"), "ログイン情報を保存できませんでした:", err)
+		} else {
+			fmt.Println("ログイン情報を保存しました。")
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,104 @@
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// helperPrefix 名前だけで指定したヘルパーのコマンド名の接頭辞 (例: pass → freeedom-credential-pass)
+const helperPrefix = "freeedom-credential-"
+
+// helper 外部コマンドのヘルパーを実行する
+// git credential と同じく、アクション (get, store) を引数に付けて実行し、標準入力に key=value の行を渡す
+//
+//	protocol=https
+//	host=member.bookwalker.jp
+//	site=bookwalker
+//	username=user@example.com
+//
+// get では標準出力に username=... と password=... を返す (見つからなければ何も返さない)
+func (r *Resolver) helper(ctx context.Context, action string, c *Credentials) (*Credentials, error) {
+	username := r.Account
+	if c != nil && c.Email != "" {
+		username = c.Email
+	}
+	attrs := [][2]string{{"protocol", "https"}}
+	if r.Host != "" {
+		attrs = append(attrs, [2]string{"host", r.Host})
+	}
+	attrs = append(attrs, [2]string{"site", r.Site})
+	if username != "" {
+		attrs = append(attrs, [2]string{"username", username})
+	}
+	if c != nil && action != "get" {
+		attrs = append(attrs, [2]string{"password", c.Password})
+	}
+
+	input := &bytes.Buffer{}
+	for _, attr := range attrs {
+		// 改行を含む値はヘルパーに別の行 (別の key=value) として読まれてしまう
+		if strings.ContainsAny(attr[1], "\n\r\x00") {
+			return nil, fmt.Errorf("ログイン情報のヘルパーに渡す %s に改行やNUL文字を含めることはできません", attr[0])
+		}
+		fmt.Fprintf(input, "%s=%s\n", attr[0], attr[1])
+	}
+	fmt.Fprintln(input)
+
+	cmd := helperCommand(ctx, r.Helper, action)
+	cmd.Stdin = input
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ログイン情報のヘルパーの実行に失敗しました (%s %s): %w", r.Helper, action, err)
+	}
+	if action != "get" {
+		return nil, nil
+	}
+
+	got := &Credentials{Email: username}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			got.Email = value
+		case "password":
+			got.Password = value
+		case "quit":
+			if value == "1" || value == "true" {
+				return nil, fmt.Errorf("ログイン情報のヘルパーが中断しました (%s)", r.Helper)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if got.Password != "" && got.Email == "" {
+		return nil, fmt.Errorf("ログイン情報のヘルパーが username を返しませんでした (%s)", r.Helper)
+	}
+	return got, nil
+}
+
+// helperCommand ヘルパーのコマンドを作成する
+// 名前だけの場合は PATH 上の freeedom-credential-{名前}、! で始まる場合や空白・パスを含む場合はシェルで実行する
+func helperCommand(ctx context.Context, helper, action string) *exec.Cmd {
+	shell := strings.HasPrefix(helper, "!") || strings.ContainsAny(helper, " /\\")
+	if !shell {
+		return exec.CommandContext(ctx, helperPrefix+helper, action)
+	}
+
+	line := strings.TrimPrefix(helper, "!") + " " + action
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/c", line)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
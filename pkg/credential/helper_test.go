@@ -0,0 +1,152 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeHelper 標準入力を input に書き出し、FAKE_HELPER_OUTPUT を標準出力に返すヘルパーのシェルスクリプト
+// FAKE_HELPER_EXIT を指定すると、その終了コードで終わる
+const fakeHelper = `#!/bin/sh
+cat > "$FAKE_HELPER_DIR/input-$1"
+printf '%b' "$FAKE_HELPER_OUTPUT"
+exit "${FAKE_HELPER_EXIT:-0}"
+`
+
+// newFakeHelper 一時ディレクトリに置いたヘルパーを使う Resolver
+func newFakeHelper(t *testing.T, account, output string) (*Resolver, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script helper is not supported on windows")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "helper.sh")
+	if err := os.WriteFile(path, []byte(fakeHelper), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_HELPER_DIR", dir)
+	t.Setenv("FAKE_HELPER_OUTPUT", output)
+	t.Setenv("FAKE_HELPER_EXIT", "")
+
+	r := New("example", "https://login.example.com/signin", Options{Helper: path, Account: account})
+	return r, dir
+}
+
+func readInput(t *testing.T, dir, action string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, "input-"+action))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHelperGet(t *testing.T) {
+	r, dir := newFakeHelper(t, "", `username=user@example.com\npassword=p@ss=word\nunknown=1\n`)
+	c, err := r.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Email != "user@example.com" || c.Password != "p@ss=word" || c.source != "helper" {
+		t.Errorf("Get() = %+v", c)
+	}
+
+	if got, want := readInput(t, dir, "get"), "protocol=https\nhost=login.example.com\nsite=example\n\n"; got != want {
+		t.Errorf("input = %q, want %q", got, want)
+	}
+}
+
+// TestHelperGetAccount --credential-account を指定した場合はヘルパーが username を返さなくてもよい
+func TestHelperGetAccount(t *testing.T) {
+	r, dir := newFakeHelper(t, "user@example.com", `password=secret\n`)
+	c, err := r.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Email != "user@example.com" || c.Password != "secret" {
+		t.Errorf("Get() = %+v", c)
+	}
+	if input := readInput(t, dir, "get"); !strings.Contains(input, "username=user@example.com\n") {
+		t.Errorf("input = %q, want username", input)
+	}
+}
+
+func TestHelperGetWithoutUsername(t *testing.T) {
+	r, _ := newFakeHelper(t, "", `password=secret\n`)
+	if c, err := r.Get(context.Background()); err == nil {
+		t.Errorf("Get() = %+v, want error", c)
+	}
+}
+
+// TestHelperGetNotFound ヘルパーが何も返さなければ次の取得元 (環境変数) を使う
+func TestHelperGetNotFound(t *testing.T) {
+	r, _ := newFakeHelper(t, "", "")
+	t.Setenv("FREEEDOM_EXAMPLE_EMAIL", "env@example.com")
+	t.Setenv("FREEEDOM_EXAMPLE_PASSWORD", "env-secret")
+	c, err := r.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Email != "env@example.com" || c.source != "env" {
+		t.Errorf("Get() = %+v", c)
+	}
+}
+
+func TestHelperErrors(t *testing.T) {
+	t.Run("quit", func(t *testing.T) {
+		r, _ := newFakeHelper(t, "", `quit=1\n`)
+		if _, err := r.Get(context.Background()); err == nil {
+			t.Error("want error")
+		}
+	})
+	t.Run("exit code", func(t *testing.T) {
+		r, _ := newFakeHelper(t, "", `username=user@example.com\npassword=secret\n`)
+		t.Setenv("FAKE_HELPER_EXIT", "1")
+		if _, err := r.Get(context.Background()); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestHelperStore(t *testing.T) {
+	r, dir := newFakeHelper(t, "", "")
+	if err := r.Approve(context.Background(), &Credentials{Email: "user@example.com", Password: "secret", source: "prompt"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "protocol=https\nhost=login.example.com\nsite=example\nusername=user@example.com\npassword=secret\n\n"
+	if got := readInput(t, dir, "store"); got != want {
+		t.Errorf("input = %q, want %q", got, want)
+	}
+
+	// ヘルパー以外から取得したログイン情報は保存させない
+	os.Remove(filepath.Join(dir, "input-store"))
+	if err := r.Approve(context.Background(), &Credentials{Email: "user@example.com", Password: "secret", source: "env"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "input-store")); err == nil {
+		t.Error("stored credentials from env")
+	}
+}
+
+// TestHelperRejectNewline 改行を含む値を渡すと別の key=value として読まれるので、ヘルパーを実行しない
+func TestHelperRejectNewline(t *testing.T) {
+	for _, c := range []*Credentials{
+		{Email: "user@example.com", Password: "secret\npassword=other"},
+		{Email: "user@example.com\nhost=evil.example.com", Password: "secret"},
+		{Email: "user@example.com", Password: "secret\r"},
+	} {
+		r, dir := newFakeHelper(t, "", "")
+		c.source = "prompt"
+		if err := r.Approve(context.Background(), c); err == nil {
+			t.Errorf("Approve(%q, %q): want error", c.Email, c.Password)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "input-store")); err == nil {
+			t.Errorf("Approve(%q, %q): the helper was executed", c.Email, c.Password)
+		}
+	}
+}
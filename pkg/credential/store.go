@@ -0,0 +1,59 @@
+package credential
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/JINZO631/freeedom/pkg/secret"
+)
+
+// Load 暗号化して保存したログイン情報を読み込む (保存していない場合は nil)
+func Load(site, account string) (*Credentials, error) {
+	path, err := secret.SitePath("credentials", site, account)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credentials{}
+	if err := secret.LoadJSON(path, c); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save ログイン情報を暗号化して保存する
+func Save(site, account string, c *Credentials) error {
+	path, err := secret.SitePath("credentials", site, account)
+	if err != nil {
+		return err
+	}
+	return secret.SaveJSON(path, c)
+}
+
+// Remove 保存したログイン情報を削除する
+func Remove(site, account string) error {
+	path, err := secret.SitePath("credentials", site, account)
+	if err != nil {
+		return err
+	}
+	if err := secret.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Forget サイトの全てのアカウントの保存したログイン情報を削除する
+func Forget(site string) error {
+	if err := Remove(site, ""); err != nil {
+		return err
+	}
+	path, err := secret.SitePath("credentials", site, "")
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(filepath.Dir(path), site))
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/JINZO631/freeedom/pkg/journal"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+// WriteFunc 仕訳を会計ソフトのインポート形式で書き出す関数
+type WriteFunc func(w io.Writer, entries []*journal.Entry) error
+
+// formats 出力形式の名前と書き出す関数の対応
+var formats = map[string]WriteFunc{
+	"freee-csv": WriteFreeeCSV,
+	"mf-csv":    WriteMoneyForwardCSV,
+	"yayoi":     WriteYayoi,
+}
+
+// Formats 対応している出力形式の名前の一覧
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write 指定した出力形式で仕訳を書き出す
+func Write(w io.Writer, format string, entries []*journal.Entry) error {
+	write, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("対応していない出力形式です: %s (対応形式: %v)", format, Formats())
+	}
+	// 会計ソフトの金額はすべて円として読み込まれるため、外貨建ての仕訳は出力しない
+	for _, e := range entries {
+		if e.Receipt != nil && e.Receipt.Currency != "" {
+			return fmt.Errorf("外貨建ての仕訳は出力できません: %s (%s)", e.ID, receipt.FormatMoney(e.Receipt.Total, e.Receipt.Currency))
+		}
+	}
+	return write(w, entries)
+}
+
+// shiftJISWriter Shift_JISでしか読み込めない会計ソフト向けに文字コードを変換するWriter
+func shiftJISWriter(w io.Writer) io.WriteCloser {
+	// Shift_JISで表せない文字 (絵文字など) は制御文字 (0x1A) にせず、見える文字に置き換える
+	return transform.NewWriter(w, transform.Chain(runes.Map(shiftJISRune), japanese.ShiftJIS.NewEncoder()))
+}
+
+// shiftJISReplacement Shift_JISで表せない文字の代わりに出力する文字
+const shiftJISReplacement = '?'
+
+// shiftJISRune Shift_JISで表せない文字を置き換える
+func shiftJISRune(r rune) rune {
+	if shiftJISLen(r) == 0 {
+		return shiftJISReplacement
+	}
+	return r
+}
+
+// shiftJISLen 文字をShift_JISにしたときのバイト数を返す (表せない文字は0)
+func shiftJISLen(r rune) int {
+	b, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(string(r)))
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// truncateShiftJIS Shift_JISにしたときに max バイトに収まるように文字列を切り詰める
+func truncateShiftJIS(s string, max int) string {
+	n := 0
+	for i, r := range s {
+		l := shiftJISLen(r)
+		if l == 0 {
+			l = 1 // shiftJISReplacement
+		}
+		if n+l > max {
+			return s[:i]
+		}
+		n += l
+	}
+	return s
+}
+
+// taxCategoryNames freeeの税区分名から各会計ソフトの税区分名への変換表
+// 対応付けの設定ファイルではfreeeの税区分名で指定し、表にない名前はそのまま出力する
+var taxCategoryNames = map[string]map[string]string{
+	"mf-csv": {
+		"課対仕入10%":   "課税仕入 10%",
+		"課対仕入8%（軽）": "課税仕入 (軽)8%",
+		"課対仕入8%":    "課税仕入 8%",
+		"非課仕入":      "非課税仕入",
+		"対象外":       "対象外",
+	},
+	"yayoi": {
+		"課対仕入10%":   "課対仕入内10%",
+		"課対仕入8%（軽）": "課対仕入内軽減8%",
+		"課対仕入8%":    "課対仕入内8%",
+		"非課仕入":      "非課仕入",
+		"対象外":       "対象外",
+	},
+}
+
+// taxCategory 出力形式に合わせた税区分名を返す
+func taxCategory(format, name string) string {
+	if converted, ok := taxCategoryNames[format][name]; ok {
+		return converted
+	}
+	return name
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/journal"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// update testdata の期待するファイルを出力結果で書き換える (go test ./pkg/export -update)
+var update = flag.Bool("update", false, "update golden files")
+
+// entries 各出力形式で扱いの違う値 (カンマ・引用符・Shift_JISにない文字・タグ・登録番号・軽減税率) を含む仕訳
+var entries = []*journal.Entry{
+	{
+		ID:                "amazon-503-1234567-1234567",
+		Date:              "2024-01-05",
+		Partner:           "アマゾンジャパン合同会社",
+		AccountItem:       "消耗品費",
+		TaxCategory:       "課対仕入10%",
+		Amount:            3980,
+		Description:       `USBケーブル, 2本 "急速充電"`,
+		Wallet:            "クレジットカード",
+		Tags:              []string{"amazon", "備品"},
+		CreditAccountItem: "未払金",
+		CreditSubAccount:  "クレジットカード",
+		BusinessRatio:     100,
+		Receipt:           &receipt.Receipt{RegistrationNumber: "T9234567890123"},
+	},
+	{
+		ID:                "ubereats-m1",
+		Date:              "2024-01-20",
+		Partner:           "Uber Eats Japan合同会社",
+		AccountItem:       "会議費",
+		TaxCategory:       "課対仕入8%（軽）",
+		Amount:            1240,
+		Description:       "打ち合わせ 🍱",
+		Wallet:            "クレジットカード",
+		CreditAccountItem: "未払金",
+		BusinessRatio:     50,
+		Receipt:           &receipt.Receipt{},
+	},
+	{
+		ID:                "bookwalker-global-1001",
+		Date:              "2024-02-01",
+		AccountItem:       "新聞図書費",
+		TaxCategory:       "不課税",
+		Amount:            1500,
+		Description:       "技術書",
+		Wallet:            "現金",
+		CreditAccountItem: "事業主借",
+		BusinessRatio:     100,
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		golden string
+	}{
+		{"freee-csv", "freee.csv"},
+		{"mf-csv", "moneyforward.csv"},
+		{"yayoi", "yayoi.csv"},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, entries); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s does not match the output:\n%q", path, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "csv", entries); err == nil {
+		t.Error("want error")
+	}
+}
+
+// TestWriteForeignCurrency 外貨建ての金額を円として書き出さないことを確認する
+func TestWriteForeignCurrency(t *testing.T) {
+	usd := &journal.Entry{
+		ID:          "bookwalker-global-1002",
+		Date:        "2024-02-02",
+		AccountItem: "新聞図書費",
+		Amount:      1234,
+		Receipt:     &receipt.Receipt{Total: 1234, Currency: "USD"},
+	}
+	for _, format := range Formats() {
+		var buf bytes.Buffer
+		err := Write(&buf, format, append(entries[:len(entries):len(entries)], usd))
+		if err == nil {
+			t.Errorf("%s: want error for USD entry, got %q", format, buf.String())
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: wrote %d bytes before the error", format, buf.Len())
+		}
+	}
+}
+
+func TestYayoiDescription(t *testing.T) {
+	for _, tt := range []struct {
+		partner     string
+		description string
+		want        string
+	}{
+		{"", "技術書", "技術書"},
+		{"Uber Eats Japan合同会社", "打ち合わせ", "Uber Eats Japan合同会社 打ち合わせ"},
+		// 全角32文字 (64バイト) で切り詰める
+		{"", strings.Repeat("あ", 40), strings.Repeat("あ", 32)},
+		// 全角文字の途中では切らない
+		{"", "a" + strings.Repeat("あ", 40), "a" + strings.Repeat("あ", 31)},
+		// Shift_JISにない文字は置き換えた後の1バイトとして数える
+		{"", strings.Repeat("🍱", 70), strings.Repeat("🍱", 64)},
+	} {
+		got := yayoiDescription(&journal.Entry{Partner: tt.partner, Description: tt.description})
+		if got != tt.want {
+			t.Errorf("yayoiDescription(%q, %q) = %q, want %q", tt.partner, tt.description, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/journal"
+)
+
+// freeeHeader freeeの取引インポート用CSVのヘッダー
+var freeeHeader = []string{
+	"収支区分",
+	"管理番号",
+	"発生日",
+	"決済期日",
+	"取引先",
+	"勘定科目",
+	"税区分",
+	"金額",
+	"税計算区分",
+	"税額",
+	"備考",
+	"品目",
+	"部門",
+	"メモタグ（複数指定可、カンマ区切り）",
+	"決済日",
+	"決済口座",
+	"決済金額",
+}
+
+// WriteFreeeCSV 仕訳をfreeeの取引インポート形式のCSVで書き出す
+// 領収書は支払済みのため、発生日に決済口座から全額決済した支出として登録する
+func WriteFreeeCSV(w io.Writer, entries []*journal.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(freeeHeader); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		amount := strconv.Itoa(e.Amount)
+		if err := cw.Write([]string{
+			"支出",
+			e.ID,
+			slashDate(e.Date),
+			"",
+			e.Partner,
+			e.AccountItem,
+			e.TaxCategory,
+			amount,
+			"内税",
+			"",
+			e.Description,
+			"",
+			"",
+			strings.Join(e.Tags, ","),
+			slashDate(e.Date),
+			e.Wallet,
+			amount,
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// slashDate 2024-01-01 を会計ソフトの日付形式 2024/01/01 に変換する
+func slashDate(date string) string {
+	return strings.ReplaceAll(date, "-", "/")
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/journal"
+)
+
+// moneyForwardHeader マネーフォワード クラウド会計の仕訳帳インポート用CSVのヘッダー
+var moneyForwardHeader = []string{
+	"取引No",
+	"取引日",
+	"借方勘定科目",
+	"借方補助科目",
+	"借方部門",
+	"借方取引先",
+	"借方税区分",
+	"借方インボイス",
+	"借方金額(円)",
+	"借方税額",
+	"貸方勘定科目",
+	"貸方補助科目",
+	"貸方部門",
+	"貸方取引先",
+	"貸方税区分",
+	"貸方インボイス",
+	"貸方金額(円)",
+	"貸方税額",
+	"摘要",
+	"仕訳メモ",
+	"タグ",
+	"MF仕訳タイプ",
+	"決算整理仕訳",
+}
+
+// WriteMoneyForwardCSV 仕訳をマネーフォワード クラウド会計の仕訳帳インポート形式のCSVで書き出す
+// 借方に経費の勘定科目、貸方に決済口座の勘定科目を立てた1行の仕訳にする
+func WriteMoneyForwardCSV(w io.Writer, entries []*journal.Entry) error {
+	sw := shiftJISWriter(w)
+	cw := csv.NewWriter(sw)
+	if err := cw.Write(moneyForwardHeader); err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		amount := strconv.Itoa(e.Amount)
+		if err := cw.Write([]string{
+			strconv.Itoa(i + 1),
+			slashDate(e.Date),
+			e.AccountItem,
+			"",
+			"",
+			e.Partner,
+			taxCategory("mf-csv", e.TaxCategory),
+			invoiceFlag(e),
+			amount,
+			"",
+			e.CreditAccountItem,
+			e.CreditSubAccount,
+			"",
+			"",
+			"対象外",
+			"",
+			amount,
+			"",
+			e.Description,
+			e.ID,
+			strings.Join(e.Tags, "|"),
+			"",
+			"",
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// invoiceFlag 登録番号が記載された領収書には適格請求書であることを示す値を返す
+func invoiceFlag(e *journal.Entry) string {
+	if e.Receipt != nil && e.Receipt.RegistrationNumber != "" {
+		return "適格"
+	}
+	return ""
+}
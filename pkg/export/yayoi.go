@@ -0,0 +1,71 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/JINZO631/freeedom/pkg/journal"
+)
+
+// yayoiSingleEntry 弥生インポート形式の識別フラグ (1行で完結する仕訳)
+const yayoiSingleEntry = "2000"
+
+// yayoiDescriptionBytes 摘要の最大の長さ (Shift_JISで64バイト、全角32文字)
+const yayoiDescriptionBytes = 64
+
+// WriteYayoi 仕訳を弥生会計の弥生インポート形式 (ヘッダーなし・Shift_JIS・25列) で書き出す
+func WriteYayoi(w io.Writer, entries []*journal.Entry) error {
+	sw := shiftJISWriter(w)
+	cw := csv.NewWriter(sw)
+	cw.UseCRLF = true
+
+	for i, e := range entries {
+		amount := strconv.Itoa(e.Amount)
+		if err := cw.Write([]string{
+			yayoiSingleEntry,                    // 識別フラグ
+			strconv.Itoa(i + 1),                 // 伝票No
+			"",                                  // 決算
+			slashDate(e.Date),                   // 取引日付
+			e.AccountItem,                       // 借方勘定科目
+			"",                                  // 借方補助科目
+			"",                                  // 借方部門
+			taxCategory("yayoi", e.TaxCategory), // 借方税区分
+			amount,                              // 借方金額
+			"",                                  // 借方税金額
+			e.CreditAccountItem,                 // 貸方勘定科目
+			e.CreditSubAccount,                  // 貸方補助科目
+			"",                                  // 貸方部門
+			"対象外",                               // 貸方税区分
+			amount,                              // 貸方金額
+			"",                                  // 貸方税金額
+			yayoiDescription(e),                 // 摘要
+			"",                                  // 番号
+			"",                                  // 期日
+			"0",                                 // タイプ
+			"",                                  // 生成元
+			e.ID,                                // 仕訳メモ
+			"0",                                 // 付箋1
+			"0",                                 // 付箋2
+			"no",                                // 調整
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// yayoiDescription 弥生には取引先の列がないので摘要の先頭に取引先を入れる
+// 長すぎる摘要はインポートでエラーになるため、最大の長さで切り詰める
+func yayoiDescription(e *journal.Entry) string {
+	description := e.Description
+	if e.Partner != "" {
+		description = e.Partner + " " + e.Description
+	}
+	return truncateShiftJIS(description, yayoiDescriptionBytes)
+}
@@ -0,0 +1,170 @@
+// Package extract メール本文のhtmlから領収書の情報を取り出すための関数
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Parse htmlをパースする
+func Parse(s string) (*html.Node, error) {
+	return html.Parse(strings.NewReader(s))
+}
+
+// skipElements テキストとして扱わない要素
+var skipElements = map[string]bool{
+	"head":     true,
+	"style":    true,
+	"script":   true,
+	"template": true,
+}
+
+// blockElements 前後で行を区切る要素 (それ以外の要素の中のテキストは同じ行として連結する)
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"caption": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "ul": true,
+}
+
+// Lines 要素の子孫のテキストを行ごとに返す
+// <span> や <b> で分割されたテキストは連結し、ブロック要素の境界で行を区切る。
+// 行内の空白 (改行・&nbsp;・全角スペースを含む) は1つの半角スペースにまとめ、空の行は除く
+func Lines(n *html.Node) []string {
+	if n == nil {
+		return nil
+	}
+
+	lines := []string{}
+	var sb strings.Builder
+	flush := func() {
+		if line := normalize(sb.String()); line != "" {
+			lines = append(lines, line)
+		}
+		sb.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if skipElements[n.Data] {
+				return
+			}
+			if blockElements[n.Data] {
+				flush()
+				defer flush()
+			}
+		case html.CommentNode, html.DoctypeNode:
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	flush()
+
+	return lines
+}
+
+// Text 要素の子孫のテキストを空白を正規化して1つの文字列にする (行の区切りは半角スペース)
+func Text(n *html.Node) string {
+	return strings.Join(Lines(n), " ")
+}
+
+// normalize 連続する空白を1つの半角スペースにまとめ、前後の空白を除く
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Attr 要素の属性の値を返す (属性がない場合は空文字)
+func Attr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Selector コンパイル済みのCSSセレクタ
+type Selector struct {
+	sel cascadia.Sel
+}
+
+// Compile CSSセレクタをコンパイルする
+func Compile(selector string) (*Selector, error) {
+	sel, err := cascadia.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Selector{sel: sel}, nil
+}
+
+// MustCompile CSSセレクタをコンパイルする (不正なセレクタの場合はpanicする)
+// パッケージ変数の初期化など、セレクタが固定の場合に使う
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(`extract: Compile(` + selector + `): ` + err.Error())
+	}
+	return s
+}
+
+// All セレクタに一致する子孫の要素を全て返す
+func (s *Selector) All(n *html.Node) []*html.Node {
+	if n == nil {
+		return nil
+	}
+	return cascadia.QueryAll(n, s.sel)
+}
+
+// First セレクタに一致する最初の子孫の要素を返す (見つからない場合はnil)
+func (s *Selector) First(n *html.Node) *html.Node {
+	if n == nil {
+		return nil
+	}
+	return cascadia.Query(n, s.sel)
+}
+
+// ValueNear ラベルの近くにある値を探す
+// ラベルで始まる行を探し、その行のラベルより後ろ、続く window 行の順に正規表現に一致する値を探す。
+// 正規表現にグループがある場合は1つ目のグループの値を返す。見つからない場合は空文字を返す
+func ValueNear(lines []string, label string, re *regexp.Regexp, window int) string {
+	for i, line := range lines {
+		if !strings.HasPrefix(line, label) {
+			continue
+		}
+
+		candidates := append([]string{strings.TrimPrefix(line, label)}, lines[i+1:min(i+1+window, len(lines))]...)
+		for _, c := range candidates {
+			if v := match(re, c); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// match 正規表現に一致した値を返す (グループがある場合は1つ目のグループの値)
+func match(re *regexp.Regexp, s string) string {
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	if len(m) > 1 {
+		return m[1]
+	}
+	return m[0]
+}
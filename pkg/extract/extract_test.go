@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestLines(t *testing.T) {
+	doc := parseFile(t, "nested.html")
+	want := []string{
+		"合計 ￥1,234",
+		"お支払日 2024年3月9日",
+		"商品A",
+		"商品B",
+		"ご利用",
+		"ありがとうございました",
+		"PDF をダウンロード",
+	}
+	if got := Lines(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+	if got := Lines(nil); got != nil {
+		t.Errorf("Lines(nil) = %q", got)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	doc := parseFile(t, "nested.html")
+	a := MustCompile("a[href]").First(doc)
+	if got := Text(a); got != "PDF をダウンロード" {
+		t.Errorf("Text(a) = %q", got)
+	}
+	if got := Attr(a, "href"); got != "https://example.com/receipt.pdf" {
+		t.Errorf("Attr(a, href) = %q", got)
+	}
+	if got := len(MustCompile("li").All(doc)); got != 2 {
+		t.Errorf("len(li) = %d", got)
+	}
+	if got := MustCompile("table").First(doc); got != nil {
+		t.Errorf("First(table) = %v", got)
+	}
+	if _, err := Compile("a[href"); err == nil {
+		t.Error("Compile: want error")
+	}
+}
+
+func TestValueNear(t *testing.T) {
+	amount := regexp.MustCompile(`￥([\d,]+)`)
+	for _, tt := range []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"合計 ￥1,234"}, "1,234"},
+		{[]string{"合計", "(税込)", "￥1,234"}, "1,234"},
+		{[]string{"合計", "(税込)", "(送料込)", "￥1,234"}, ""},
+		{[]string{"小計 ￥1,000", "合計", "￥1,234"}, "1,234"},
+		{[]string{"合計"}, ""},
+		{nil, ""},
+	} {
+		if got := ValueNear(tt.lines, "合計", amount, 2); got != tt.want {
+			t.Errorf("ValueNear(%q) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
+
+// FuzzLines 壊れたhtmlや空の要素でもpanicせず、正規化した行だけを返すことを確認する
+func FuzzLines(f *testing.F) {
+	addSeeds(f, "testdata")
+	f.Add("")
+	f.Add("<span>")
+	f.Add("<a href=x></a><b></b>")
+	f.Add("<table><tr><td>合計<td>￥1,234</table>")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		doc, err := Parse(s)
+		if err != nil {
+			return
+		}
+		for _, line := range Lines(doc) {
+			if line == "" || line != strings.Join(strings.Fields(line), " ") {
+				t.Errorf("Lines: not normalized: %q", line)
+			}
+		}
+		for _, a := range MustCompile("a").All(doc) {
+			Attr(a, "href")
+			Text(a)
+		}
+	})
+}
+
+// addSeeds ディレクトリのhtmlファイルをファズテストのシードにする
+func addSeeds(f *testing.F, dir string) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(b))
+	}
+}
+
+func parseFile(t *testing.T, name string) *html.Node {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
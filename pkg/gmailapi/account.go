@@ -0,0 +1,112 @@
+package gmailapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"google.golang.org/api/gmail/v1"
+)
+
+// DefaultAccount 名前を指定しない場合のアカウント
+// トークンは複数アカウントに対応する前と同じ gmail/token.json に保存する
+const DefaultAccount = "default"
+
+// serviceAccountPrefix サービスアカウントで代理アクセスするユーザーのアカウント名の接頭辞
+// OAuthで認証したアカウントと同じメールアドレスでも、差分同期の状態を別のディレクトリに保存する
+const serviceAccountPrefix = "service-account:"
+
+// ServiceAccountName サービスアカウントで代理アクセスするユーザーのアカウント名
+func ServiceAccountName(user string) string {
+	return serviceAccountPrefix + user
+}
+
+// accountNameRe アカウント名に使える文字 (ディレクトリ名に使うため)
+var accountNameRe = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// AccountDir アカウントのトークンなどを保存するディレクトリを取得する
+// default アカウントは gmail/、サービスアカウントで代理アクセスするユーザーは gmail/service-accounts/{メールアドレス}/、
+// それ以外は gmail/accounts/{アカウント名}/
+func AccountDir(account string) (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	if account == "" || account == DefaultAccount {
+		return filepath.Join(configDirPath, "gmail"), nil
+	}
+	dir := "accounts"
+	if user, ok := strings.CutPrefix(account, serviceAccountPrefix); ok {
+		dir, account = "service-accounts", user
+	}
+	if !accountNameRe.MatchString(account) || account == "." || account == ".." {
+		return "", fmt.Errorf("アカウント名には英数字と . _ @ - のみ使えます: %s", account)
+	}
+	return filepath.Join(configDirPath, "gmail", dir, account), nil
+}
+
+// Accounts トークンが保存されているアカウントの一覧を取得する
+func Accounts() ([]string, error) {
+	names := []string{DefaultAccount}
+
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(configDirPath, "gmail", "accounts"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+
+	// ログアウトしたアカウントはディレクトリが残っていても除く
+	accounts := []string{}
+	for _, name := range names {
+		for _, scope := range Scopes() {
+			has, err := HasGmailAPIToken(name, scope)
+			if err != nil {
+				return nil, err
+			}
+			if has {
+				accounts = append(accounts, name)
+				break
+			}
+		}
+	}
+
+	return accounts, nil
+}
+
+// ResolveAccounts 対象にするアカウントを決める
+// 指定がなければトークンが保存されている全てのアカウント、それもなければ default アカウントを使う
+func ResolveAccounts(accounts []string) ([]string, error) {
+	if len(accounts) > 0 {
+		return accounts, nil
+	}
+
+	saved, err := Accounts()
+	if err != nil {
+		return nil, err
+	}
+	if len(saved) == 0 {
+		return []string{DefaultAccount}, nil
+	}
+	return saved, nil
+}
+
+// EmailAddress 認証したGoogleアカウントのメールアドレスを取得する
+func EmailAddress(srv *gmail.Service) (string, error) {
+	profile, err := srv.Users.GetProfile("me").Do()
+	if err != nil {
+		return "", fmt.Errorf("Googleアカウントの情報を取得できませんでした: %w", err)
+	}
+	return profile.EmailAddress, nil
+}
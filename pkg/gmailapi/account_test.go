@@ -0,0 +1,41 @@
+package gmailapi
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+)
+
+func TestAccountDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gmailDir := filepath.Join(configDirPath, "gmail")
+
+	for _, tt := range []struct {
+		account string
+		want    string
+	}{
+		{"", gmailDir},
+		{DefaultAccount, gmailDir},
+		{"taro@example.com", filepath.Join(gmailDir, "accounts", "taro@example.com")},
+		// 同じメールアドレスでもサービスアカウントの代理アクセスは別のディレクトリに保存する
+		{ServiceAccountName("taro@example.com"), filepath.Join(gmailDir, "service-accounts", "taro@example.com")},
+	} {
+		got, err := AccountDir(tt.account)
+		if err != nil || got != tt.want {
+			t.Errorf("AccountDir(%q) = %q, %v, want %q", tt.account, got, err, tt.want)
+		}
+	}
+
+	for _, account := range []string{"..", "a/b", ServiceAccountName(".."), ServiceAccountName("a/b"), ServiceAccountName("")} {
+		if _, err := AccountDir(account); err == nil {
+			t.Errorf("AccountDir(%q): want error", account)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package gmailapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenStatus 保存されているトークンの状態
+type TokenStatus struct {
+	Account         string    // アカウントの名前
+	Email           string    // 認証したGoogleアカウントのメールアドレス (不明な場合は空)
+	Scope           string    // 権限
+	Path            string    // トークンのパス
+	Expiry          time.Time // アクセストークンの有効期限
+	HasRefreshToken bool      // リフレッシュトークンがあるか (ない場合は有効期限が切れると認証し直す必要がある)
+}
+
+// Scopes トークンを保存する権限の一覧
+func Scopes() []string {
+	return []string{ReadonlyScope, ModifyScope}
+}
+
+// Status アカウントの保存されているトークンの状態を権限ごとに取得する
+func Status(account string) ([]*TokenStatus, error) {
+	email, err := LoadAccountEmail(account)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*TokenStatus{}
+	for _, scope := range Scopes() {
+		tokenPath, err := GmailAPITokenPath(account, scope)
+		if err != nil {
+			return nil, err
+		}
+		token, err := GetTokenFromFile(tokenPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		statuses = append(statuses, &TokenStatus{
+			Account:         account,
+			Email:           email,
+			Scope:           scope,
+			Path:            tokenPath,
+			Expiry:          token.Expiry,
+			HasRefreshToken: token.RefreshToken != "",
+		})
+	}
+	return statuses, nil
+}
+
+// Login ブラウザで認証し直してトークンを保存する (保存されているトークンは置き換える)
+// 認証したGoogleアカウントのメールアドレスを返す
+func Login(ctx context.Context, oauthClientJSONPath, account, scope string) (string, error) {
+	config, err := OAuthConfig(oauthClientJSONPath, scope)
+	if err != nil {
+		return "", err
+	}
+	token, err := GetTokenFromWeb(ctx, account, config)
+	if err != nil {
+		return "", err
+	}
+	return connectedEmail(ctx, account, config, token)
+}
+
+// Refresh 保存されているトークンをリフレッシュする
+// リフレッシュトークンが失効している場合は ErrTokenRevoked を返す (auth login で認証し直す)
+func Refresh(ctx context.Context, oauthClientJSONPath, account, scope string) (*oauth2.Token, error) {
+	config, err := OAuthConfig(oauthClientJSONPath, scope)
+	if err != nil {
+		return nil, err
+	}
+	tokenPath, err := GmailAPITokenPath(account, scope)
+	if err != nil {
+		return nil, err
+	}
+	token, err := GetTokenFromFile(tokenPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("アカウント (%s) のトークンが保存されていません。先に auth login を実行してください", account)
+		}
+		return nil, err
+	}
+
+	// 有効期限が残っていてもリフレッシュするために、アクセストークンを期限切れにする
+	token.Expiry = time.Now().Add(-time.Minute)
+	token, err = RefreshToken(ctx, account, config, token)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := connectedEmail(ctx, account, config, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Logout 保存されているトークンをGoogleで取り消してから削除する
+// 取り消しに失敗した場合もトークンは削除する (取り消しのエラーを返す)
+func Logout(ctx context.Context, account, scope string) error {
+	tokenPath, err := GmailAPITokenPath(account, scope)
+	if err != nil {
+		return err
+	}
+	token, err := GetTokenFromFile(tokenPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	revokeErr := revoke(ctx, token)
+	if err := RemoveToken(account, scope); err != nil {
+		return err
+	}
+	return revokeErr
+}
+
+// revoke トークンをGoogleで取り消す
+func revoke(ctx context.Context, token *oauth2.Token) error {
+	t := token.RefreshToken
+	if t == "" {
+		t = token.AccessToken
+	}
+
+	form := url.Values{"token": {t}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://oauth2.googleapis.com/revoke", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("トークンを取り消せませんでした: %w", err)
+	}
+	defer res.Body.Close()
+
+	// 既に失効しているトークンは 400 になる
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("トークンを取り消せませんでした: %s", res.Status)
+	}
+	return nil
+}
+
+// connectedEmail トークンで認証したGoogleアカウントのメールアドレスを取得して保存する
+func connectedEmail(ctx context.Context, account string, config *oauth2.Config, token *oauth2.Token) (string, error) {
+	srv, err := newServiceWithToken(ctx, config, token)
+	if err != nil {
+		return "", err
+	}
+	email, err := EmailAddress(srv)
+	if err != nil {
+		return "", err
+	}
+	if err := SaveAccountEmail(account, email); err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// accountInfo アカウントの情報 (auth status で表示する)
+type accountInfo struct {
+	Email string `json:"email"`
+}
+
+// accountInfoPath アカウントの情報の保存先
+func accountInfoPath(account string) (string, error) {
+	accountDir, err := AccountDir(account)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(accountDir, "account.json"), nil
+}
+
+// SaveAccountEmail アカウントで認証したGoogleアカウントのメールアドレスを保存する
+func SaveAccountEmail(account, email string) error {
+	path, err := accountInfoPath(account)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(&accountInfo{Email: email}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// LoadAccountEmail 保存されているアカウントのメールアドレスを取得する (保存されていない場合は空)
+func LoadAccountEmail(account string) (string, error) {
+	path, err := accountInfoPath(account)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	info := &accountInfo{}
+	if err := json.Unmarshal(b, info); err != nil {
+		return "", err
+	}
+	return info.Email, nil
+}
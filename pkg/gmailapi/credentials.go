@@ -0,0 +1,144 @@
+package gmailapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// Credentials GmailAPIの認証情報
+// OAuthクライアントで本人のアカウントとして認証するか、サービスアカウントでドメイン内のユーザーに代理アクセスする
+type Credentials struct {
+	OAuthClientJSON string   // OAuthクライアントのJSONのパス
+	Accounts        []string // OAuthで認証するアカウントの名前 (空の場合はトークンが保存されている全てのアカウント)
+
+	// ServiceAccountKey Google Workspaceのドメイン全体の委任を設定したサービスアカウントの鍵のJSONのパス
+	ServiceAccountKey string
+	// Impersonate サービスアカウントで代理アクセスするユーザーのメールアドレス
+	// @ で始まる場合は1行に1アドレスを書いたファイルのパスとして読み込む
+	Impersonate []string
+}
+
+// Account 認証済みのGmailのアカウント
+type Account struct {
+	Name    string // アカウントの名前 (トークンや差分同期の状態の保存先に使う)
+	Email   string // メールアドレス
+	Service *gmail.Service
+}
+
+// Connect 認証情報の全てのアカウントのGmailサービスを作成する
+// scope: ReadonlyScope か ModifyScope
+func (c *Credentials) Connect(ctx context.Context, scope string) ([]*Account, error) {
+	if c.ServiceAccountKey != "" {
+		return c.connectServiceAccount(ctx, scope)
+	}
+	if c.OAuthClientJSON == "" {
+		return nil, errors.New("GmailAPIのOAuthクライアントのJSONか、サービスアカウントの鍵を指定してください")
+	}
+
+	names, err := ResolveAccounts(c.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []*Account{}
+	for _, name := range names {
+		srv, err := NewService(ctx, c.OAuthClientJSON, name, scope)
+		if err != nil {
+			return nil, fmt.Errorf("アカウント %s: %w", name, err)
+		}
+		email, err := EmailAddress(srv)
+		if err != nil {
+			return nil, fmt.Errorf("アカウント %s: %w", name, err)
+		}
+		if err := SaveAccountEmail(name, email); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &Account{Name: name, Email: email, Service: srv})
+	}
+	return accounts, nil
+}
+
+// connectServiceAccount サービスアカウントで代理アクセスするユーザーごとにGmailサービスを作成する
+// 代理アクセスできないユーザー (退職者や委任の設定漏れなど) はエラーを表示して飛ばし、残りのユーザーで続ける
+func (c *Credentials) connectServiceAccount(ctx context.Context, scope string) ([]*Account, error) {
+	users, err := c.impersonateUsers()
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("サービスアカウントで代理アクセスするユーザーのメールアドレスを指定してください")
+	}
+
+	accounts := []*Account{}
+	for _, user := range users {
+		srv, err := NewServiceAccountService(ctx, c.ServiceAccountKey, user, scope)
+		if err != nil {
+			return nil, fmt.Errorf("ユーザー %s: %w", user, err)
+		}
+		// 代理アクセスの可否はAPIを呼ぶまで分からないので、プロフィールを取得して確かめる
+		if _, err := EmailAddress(srv); err != nil {
+			fmt.Println(color.RedString("×"), "ユーザー", user, "に代理アクセスできないため飛ばします:", err)
+			continue
+		}
+		accounts = append(accounts, &Account{Name: ServiceAccountName(user), Email: user, Service: srv})
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("サービスアカウントで代理アクセスできるユーザーがいません")
+	}
+	return accounts, nil
+}
+
+// impersonateUsers 代理アクセスするユーザーのメールアドレスを、ファイルの指定を展開して返す
+func (c *Credentials) impersonateUsers() ([]string, error) {
+	users := []string{}
+	for _, u := range c.Impersonate {
+		path, ok := strings.CutPrefix(u, "@")
+		if !ok {
+			users = append(users, u)
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("ユーザーの一覧のファイルを開けませんでした: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			users = append(users, line)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// NewServiceAccountService サービスアカウントの鍵でユーザーに代理アクセスするGmailサービスを作成する
+// Google Workspaceの管理コンソールで、サービスアカウントのクライアントIDにスコープのドメイン全体の委任を設定しておく必要がある
+func NewServiceAccountService(ctx context.Context, keyPath, user, scope string) (*gmail.Service, error) {
+	b, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	config, err := google.JWTConfigFromJSON(b, scope)
+	if err != nil {
+		return nil, fmt.Errorf("サービスアカウントの鍵を読み込めませんでした: %w", err)
+	}
+	config.Subject = user
+
+	return gmail.NewService(ctx, option.WithHTTPClient(config.Client(ctx)))
+}
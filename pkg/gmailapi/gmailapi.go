@@ -0,0 +1,186 @@
+package gmailapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// GmailAPIの権限
+const (
+	ReadonlyScope = gmail.GmailReadonlyScope // メールの読み取りのみ
+	ModifyScope   = gmail.GmailModifyScope   // ラベルの付与などメールの変更 (削除はできない)
+)
+
+// NewService OAuthクライアントのJSONからGmailサービスを作成する
+// トークンが保存されていない場合はブラウザで認証を行う
+// account: トークンを保存するアカウントの名前 (空の場合は default)、scope: ReadonlyScope か ModifyScope
+func NewService(ctx context.Context, oauthClientJSONPath, account, scope string) (*gmail.Service, error) {
+
+	// Gmailの設定を取得
+	config, err := OAuthConfig(oauthClientJSONPath, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// トークンが保存されているか確認し、保存されている場合はそれを使う
+	token, err := GetToken(ctx, account, config)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = RefreshToken(ctx, account, config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gmailサービスを作成
+	return newServiceWithToken(ctx, config, token)
+}
+
+// newServiceWithToken トークンでGmailサービスを作成する
+func newServiceWithToken(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*gmail.Service, error) {
+	client := config.Client(ctx, token)
+	return gmail.NewService(ctx, option.WithHTTPClient(client))
+}
+
+// OAuthConfig OAuthクライアントのJSONから認証の設定を作成する
+func OAuthConfig(oauthClientJSONPath, scope string) (*oauth2.Config, error) {
+	b, err := os.ReadFile(oauthClientJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	config, err := google.ConfigFromJSON(b, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// リダイレクト先のURLを設定
+	config.RedirectURL = "http://localhost:8080/callback"
+	return config, nil
+}
+
+// generateRandomState OAuth2用のランダムなstate文字列を生成する
+func generateRandomState() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// GetEmails クエリにマッチするメールを取得する
+func GetEmails(srv *gmail.Service, query string) ([]*gmail.Message, error) {
+	var messages []*gmail.Message
+
+	req := srv.Users.Messages.List("me").Q(query)
+	for {
+		res, err := req.Do()
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, res.Messages...)
+		if res.NextPageToken == "" {
+			break
+		}
+
+		req.PageToken(res.NextPageToken)
+	}
+
+	fullMessages := make([]*gmail.Message, len(messages))
+	count := len(messages)
+	bar := progressbar.Default(int64(count))
+	for i, message := range messages {
+		fullMessage, err := srv.Users.Messages.Get("me", message.Id).Do()
+		if err != nil {
+			return nil, err
+		}
+		fullMessages[i] = fullMessage
+		bar.Add(1)
+	}
+
+	return fullMessages, nil
+}
+
+// Header メールのヘッダーの値を取得する
+func Header(message *gmail.Message, name string) string {
+	if message.Payload == nil {
+		return ""
+	}
+	for _, h := range message.Payload.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// Body メールの各パートを探索し、指定したMIMEタイプの最初の本文をデコードして返す
+func Body(message *gmail.Message, mimeType string) (string, error) {
+	part := findPart(message.Payload, func(p *gmail.MessagePart) bool {
+		return p.MimeType == mimeType && p.Body != nil && p.Body.Data != ""
+	})
+	if part == nil {
+		return "", nil
+	}
+
+	data, err := base64.URLEncoding.DecodeString(part.Body.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Attachments ファイル名が付いている添付ファイルのパートを全て返す
+func Attachments(message *gmail.Message) []*gmail.MessagePart {
+	parts := []*gmail.MessagePart{}
+	walkParts(message.Payload, func(p *gmail.MessagePart) {
+		if p.Filename != "" && p.Body != nil {
+			parts = append(parts, p)
+		}
+	})
+	return parts
+}
+
+// AttachmentData 添付ファイルの中身を取得する
+func AttachmentData(srv *gmail.Service, messageID string, part *gmail.MessagePart) ([]byte, error) {
+	data := part.Body.Data
+	if part.Body.AttachmentId != "" {
+		attachment, err := srv.Users.Messages.Attachments.Get("me", messageID, part.Body.AttachmentId).Do()
+		if err != nil {
+			return nil, err
+		}
+		data = attachment.Data
+	}
+	return base64.URLEncoding.DecodeString(data)
+}
+
+func findPart(part *gmail.MessagePart, match func(*gmail.MessagePart) bool) *gmail.MessagePart {
+	var found *gmail.MessagePart
+	walkParts(part, func(p *gmail.MessagePart) {
+		if found == nil && match(p) {
+			found = p
+		}
+	})
+	return found
+}
+
+func walkParts(part *gmail.MessagePart, fn func(*gmail.MessagePart)) {
+	if part == nil {
+		return
+	}
+	fn(part)
+	for _, p := range part.Parts {
+		walkParts(p, fn)
+	}
+}
@@ -0,0 +1,140 @@
+package gmailapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrHistoryExpired 保存していた historyId が古く、差分を取得できない
+// (Gmailは一定期間より前の履歴を保持しないため、全件検索し直す必要がある)
+var ErrHistoryExpired = errors.New("GmailのhistoryIdの有効期限が切れています")
+
+// HistoryPath アカウントの差分同期の状態 (前回の実行時の historyId) の保存先を取得する
+func HistoryPath(account string) (string, error) {
+	accountDir, err := AccountDir(account)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(accountDir, "history.json"), nil
+}
+
+// LoadHistoryID 前回の実行時に保存した historyId を取得する
+// name はメールを処理したコマンドの名前 (ubereats など)。保存されていない場合は0を返す
+func LoadHistoryID(account, name string) (uint64, error) {
+	ids, err := loadHistoryIDs(account)
+	if err != nil {
+		return 0, err
+	}
+	return ids[name], nil
+}
+
+// SaveHistoryID 次回の差分同期のために historyId を保存する
+func SaveHistoryID(account, name string, historyID uint64) error {
+	ids, err := loadHistoryIDs(account)
+	if err != nil {
+		return err
+	}
+	ids[name] = historyID
+
+	historyPath, err := HistoryPath(account)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 書き込みの途中で終了しても他のコマンドの状態が消えないように、一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(historyPath), ".history.json.tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(b); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), historyPath)
+}
+
+// loadHistoryIDs 保存されている historyId を全て読み込む
+func loadHistoryIDs(account string) (map[string]uint64, error) {
+	historyPath, err := HistoryPath(account)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]uint64{}
+	b, err := os.ReadFile(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ids, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return nil, fmt.Errorf("差分同期の状態を読み込めませんでした %s: %w", historyPath, err)
+	}
+	return ids, nil
+}
+
+// CurrentHistoryID メールボックスの現在の historyId を取得する
+func CurrentHistoryID(srv *gmail.Service) (uint64, error) {
+	profile, err := srv.Users.GetProfile("me").Do()
+	if err != nil {
+		return 0, err
+	}
+	return profile.HistoryId, nil
+}
+
+// AddedMessageIDs historyId 以降にメールボックスに追加されたメールのIDを取得する
+// historyId が古すぎて履歴を取得できない場合は ErrHistoryExpired を返す
+func AddedMessageIDs(srv *gmail.Service, startHistoryID uint64) ([]string, error) {
+	ids := []string{}
+	seen := map[string]bool{}
+
+	req := srv.Users.History.List("me").StartHistoryId(startHistoryID).HistoryTypes("messageAdded")
+	for {
+		res, err := req.Do()
+		if err != nil {
+			var apiErr *googleapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+				return nil, ErrHistoryExpired
+			}
+			return nil, err
+		}
+
+		for _, h := range res.History {
+			for _, added := range h.MessagesAdded {
+				if added.Message == nil || seen[added.Message.Id] {
+					continue
+				}
+				seen[added.Message.Id] = true
+				ids = append(ids, added.Message.Id)
+			}
+		}
+		if res.NextPageToken == "" {
+			break
+		}
+
+		req.PageToken(res.NextPageToken)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,49 @@
+package gmailapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveHistoryID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	if id, err := LoadHistoryID("work", "ubereats"); err != nil || id != 0 {
+		t.Fatalf("LoadHistoryID before saving = %d, %v", id, err)
+	}
+
+	// コマンドごとの historyId を同じファイルに残す
+	if err := SaveHistoryID("work", "ubereats", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveHistoryID("work", "gmail", 200); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveHistoryID("work", "ubereats", 300); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]uint64{"ubereats": 300, "gmail": 200} {
+		if id, err := LoadHistoryID("work", name); err != nil || id != want {
+			t.Errorf("LoadHistoryID(%q) = %d, %v, want %d", name, id, err, want)
+		}
+	}
+
+	// 一時ファイルを残さない
+	historyPath, err := HistoryPath("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(historyPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "history.json" {
+		for _, e := range entries {
+			t.Log(e.Name())
+		}
+		t.Error("want only history.json")
+	}
+}
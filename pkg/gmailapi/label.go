@@ -0,0 +1,134 @@
+package gmailapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// 処理したメールに付けるラベル
+const (
+	LabelProcessed = "freeedom/processed" // 領収書を保存できたメール
+	LabelFailed    = "freeedom/failed"    // 領収書を保存できなかったメール
+)
+
+// LabelOptions 処理したメールのラベルの扱い
+type LabelOptions struct {
+	Mark          bool // 処理したメールにラベルを付ける (変更の権限が必要)
+	SkipProcessed bool // 処理済みのラベルが付いたメールを検索から除く
+}
+
+// Scope ラベルの扱いに必要なGmailAPIの権限
+func (o LabelOptions) Scope() string {
+	if o.Mark {
+		return ModifyScope
+	}
+	return ReadonlyScope
+}
+
+// Query 検索クエリに処理済みのメールを除く条件を加える
+func (o LabelOptions) Query(query string) string {
+	if !o.SkipProcessed {
+		return query
+	}
+	// 検索クエリではラベル名の / を - に置き換える
+	return strings.TrimSpace(query + " -label:" + strings.ReplaceAll(LabelProcessed, "/", "-"))
+}
+
+// Labeler 処理したメールにラベルを付ける
+type Labeler struct {
+	srv     *gmail.Service
+	options LabelOptions
+	ids     map[string]string // ラベル名とラベルID
+}
+
+// NewLabeler ラベルを付けるための Labeler を作成する
+// options.Mark が false の場合、Mark は何もしない
+func NewLabeler(srv *gmail.Service, options LabelOptions) *Labeler {
+	return &Labeler{srv: srv, options: options}
+}
+
+// Mark メールに処理済み (processed が false の場合は失敗) のラベルを付ける
+// 反対のラベルが付いていれば外す
+func (l *Labeler) Mark(messageID string, processed bool) error {
+	if !l.options.Mark {
+		return nil
+	}
+
+	add, remove := LabelProcessed, LabelFailed
+	if !processed {
+		add, remove = LabelFailed, LabelProcessed
+	}
+
+	addID, err := l.labelID(add)
+	if err != nil {
+		return err
+	}
+	removeID, err := l.labelID(remove)
+	if err != nil {
+		return err
+	}
+
+	req := &gmail.ModifyMessageRequest{
+		AddLabelIds:    []string{addID},
+		RemoveLabelIds: []string{removeID},
+	}
+	if _, err := l.srv.Users.Messages.Modify("me", messageID, req).Do(); err != nil {
+		return fmt.Errorf("メールにラベルを付けられませんでした %s: %w", messageID, err)
+	}
+	return nil
+}
+
+// labelID ラベル名からラベルIDを取得する (ラベルがなければ作成する)
+func (l *Labeler) labelID(name string) (string, error) {
+	if err := l.loadLabels(); err != nil {
+		return "", err
+	}
+
+	if id, ok := l.ids[name]; ok {
+		return id, nil
+	}
+
+	label, err := l.srv.Users.Labels.Create("me", &gmail.Label{
+		Name:                  name,
+		LabelListVisibility:   "labelShow",
+		MessageListVisibility: "show",
+	}).Do()
+	if err != nil {
+		return "", fmt.Errorf("ラベル %s を作成できませんでした: %w", name, err)
+	}
+	l.ids[name] = label.Id
+	return label.Id, nil
+}
+
+// HasLabel メールにラベルが付いているか (ラベルを作成しない)
+func (l *Labeler) HasLabel(mail *gmail.Message, name string) (bool, error) {
+	if err := l.loadLabels(); err != nil {
+		return false, err
+	}
+
+	id, ok := l.ids[name]
+	if !ok {
+		return false, nil
+	}
+	return slices.Contains(mail.LabelIds, id), nil
+}
+
+// loadLabels ラベルの一覧を取得する (取得済みの場合は何もしない)
+func (l *Labeler) loadLabels() error {
+	if l.ids != nil {
+		return nil
+	}
+
+	res, err := l.srv.Users.Labels.List("me").Do()
+	if err != nil {
+		return fmt.Errorf("ラベルの一覧を取得できませんでした: %w", err)
+	}
+	l.ids = map[string]string{}
+	for _, label := range res.Labels {
+		l.ids[label.Name] = label.Id
+	}
+	return nil
+}
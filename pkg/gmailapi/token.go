@@ -0,0 +1,311 @@
+package gmailapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"github.com/JINZO631/freeedom/pkg/secret"
+	"golang.org/x/oauth2"
+)
+
+// GmailAPITokenPath GmailAPIのトークンのパスを取得する
+// トークンはアカウントごとに保存し、変更の権限 (ラベルの付与など) のトークンは読み取り専用のトークンとは別に保存する
+func GmailAPITokenPath(account, scope string) (string, error) {
+
+	accountDir, err := AccountDir(account)
+	if err != nil {
+		return "", err
+	}
+
+	fileName := "token.json"
+	if scope == ModifyScope {
+		fileName = "token_modify.json"
+	}
+
+	tokenPath := filepath.Join(accountDir, fileName)
+	return tokenPath, nil
+}
+
+// migrateLegacyToken UberEats専用だった頃の保存先 (ubereats/token.json) にあるトークンを移動する
+func migrateLegacyToken() error {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return err
+	}
+
+	tokenPath, err := GmailAPITokenPath(DefaultAccount, ReadonlyScope)
+	if err != nil {
+		return err
+	}
+
+	legacyPath := filepath.Join(configDirPath, "/ubereats/token.json")
+	if _, err := os.Stat(legacyPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if has, err := secret.Exists(tokenPath); err != nil || has {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0o700); err != nil {
+		return err
+	}
+	// 移動したトークンは読み込むときに暗号化する
+	return os.Rename(legacyPath, tokenPath)
+}
+
+// HasGmailAPIToken GmailAPIのトークンが保存されているか確認する
+func HasGmailAPIToken(account, scope string) (bool, error) {
+	tokenPath, err := GmailAPITokenPath(account, scope)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.Exists(tokenPath)
+}
+
+// GetToken アカウントのトークンを取得する
+func GetToken(ctx context.Context, account string, config *oauth2.Config) (*oauth2.Token, error) {
+
+	if err := migrateLegacyToken(); err != nil {
+		return nil, err
+	}
+
+	// ローカルにトークンが保存されているばそれを、保存されていない場合はブラウザを開いて認証を行う
+	hasToken, err := HasGmailAPIToken(account, scope(config))
+	if err != nil {
+		return nil, err
+	}
+
+	if hasToken {
+		// ローカルのトークンを読み込む
+		tokenPath, err := GmailAPITokenPath(account, scope(config))
+		if err != nil {
+			return nil, err
+		}
+
+		return GetTokenFromFile(tokenPath)
+	}
+
+	// ブラウザからトークンを取得
+	token, err := GetTokenFromWeb(ctx, account, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// ErrTokenRevoked リフレッシュトークンが失効している (取り消された・期限切れ)
+var ErrTokenRevoked = errors.New("トークンが失効しています")
+
+// RefreshToken トークンをリフレッシュする
+// リフレッシュトークンが失効している場合は、auth login で認証し直すように ErrTokenRevoked を返す
+// (同期の途中で時間制限なしにブラウザの操作を待たないように、ここでは認証し直さない)
+// 通信エラーなどで失敗した場合は保存しているトークンを残したままエラーを返す
+func RefreshToken(ctx context.Context, account string, config *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error) {
+	tokenSource := config.TokenSource(ctx, token)
+	token, err := tokenSource.Token()
+	if err != nil {
+		if IsRevoked(err) {
+			return nil, fmt.Errorf("アカウント (%s) の%w。%s を実行して認証し直してください", account, ErrTokenRevoked, loginCommand(account, scope(config)))
+		}
+		return nil, fmt.Errorf("トークンをリフレッシュできませんでした: %w", err)
+	}
+
+	// トークンを保存
+	if err := SaveToken(account, scope(config), token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// loginCommand アカウントのトークンを取得し直すコマンド
+func loginCommand(account, scope string) string {
+	command := "freeedom auth login -g {クライアントJSON} --account " + account
+	if scope == ModifyScope {
+		command += " --scope modify"
+	}
+	return command
+}
+
+// IsRevoked トークンのリフレッシュのエラーが、リフレッシュトークンの失効によるものか
+func IsRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	return retrieveErr.ErrorCode == "invalid_grant" || strings.Contains(string(retrieveErr.Body), "invalid_grant")
+}
+
+// SaveToken トークンを暗号化してファイルに保存する
+func SaveToken(account, scope string, token *oauth2.Token) error {
+	tokenPath, err := GmailAPITokenPath(account, scope)
+	if err != nil {
+		return err
+	}
+
+	return secret.SaveJSON(tokenPath, token)
+}
+
+// RemoveToken トークンを削除する
+func RemoveToken(account, scope string) error {
+	tokenPath, err := GmailAPITokenPath(account, scope)
+	if err != nil {
+		return err
+	}
+
+	return secret.Remove(tokenPath)
+}
+
+// GetTokenFromFile 暗号化して保存したファイルからトークンを取得する
+// 平文で保存されているトークンは暗号化して保存し直す
+func GetTokenFromFile(tokenPath string) (*oauth2.Token, error) {
+	token := &oauth2.Token{}
+	if err := secret.LoadJSON(tokenPath, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// authTimeout ブラウザでの認証の完了を待つ時間
+const authTimeout = 5 * time.Minute
+
+// authResult 認証コードのコールバックの結果
+type authResult struct {
+	token *oauth2.Token
+	err   error
+}
+
+// GetTokenFromWeb ブラウザから認証を行いトークンを取得する
+func GetTokenFromWeb(ctx context.Context, account string, config *oauth2.Config) (*oauth2.Token, error) {
+	// 認証コードを取得するためのURLを生成
+	oauthState, err := generateRandomState()
+	if err != nil {
+		return nil, err
+	}
+	authURL := config.AuthCodeURL(oauthState, oauth2.AccessTypeOffline)
+
+	// 認証コードを取得するためのサーバーを起動
+	// 結果は最初の1つだけを受け取る
+	results := make(chan authResult, 1)
+	// 複数のアカウントを続けて認証できるように、呼び出しごとにハンドラーを作る
+	mux := http.NewServeMux()
+	mux.Handle("/callback", callbackHandler(config, oauthState, results))
+	srv := &http.Server{Addr: ":8080", Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			sendResult(results, authResult{err: fmt.Errorf("認証コードを受け取るサーバーを起動できませんでした: %w", err)})
+		}
+	}()
+	defer func() {
+		// サーバーをシャットダウン
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	// ブラウザで認証ページを開き、操作の完了を待つ
+	fmt.Printf("ブラウザでメール取得を行うGoogleアカウント (%s) の認証を行ってください。\n", account)
+	if err := OpenURL(authURL); err != nil {
+		return nil, err
+	}
+
+	var result authResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(authTimeout):
+		return nil, fmt.Errorf("ブラウザでの認証が %s 以内に完了しませんでした", authTimeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	fmt.Println("トークンを取得しました。")
+
+	// トークンを保存
+	if err := SaveToken(account, scope(config), result.token); err != nil {
+		return nil, err
+	}
+
+	return result.token, nil
+}
+
+// callbackHandler 認証後にリダイレクトされるURLで認証コードをトークンに交換し、結果を results に送る
+func callbackHandler(config *oauth2.Config, oauthState string, results chan<- authResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 認証コード取得時にstateをチェック
+		if r.FormValue("state") != oauthState {
+			http.Error(w, "state が一致しません", http.StatusBadRequest)
+			sendResult(results, authResult{err: errors.New("認証のコールバックの state が一致しません")})
+			return
+		}
+		if message := r.FormValue("error"); message != "" {
+			http.Error(w, "認証が拒否されました", http.StatusForbidden)
+			sendResult(results, authResult{err: fmt.Errorf("認証が拒否されました: %s", message)})
+			return
+		}
+
+		// 認証コードをトークンに交換する
+		token, err := config.Exchange(r.Context(), r.FormValue("code"))
+		if err != nil {
+			http.Error(w, "トークンを取得できませんでした", http.StatusInternalServerError)
+			sendResult(results, authResult{err: fmt.Errorf("認証コードをトークンに交換できませんでした: %w", err)})
+			return
+		}
+
+		fmt.Fprintln(w, "認証が完了しました。このページを閉じてください。")
+		sendResult(results, authResult{token: token})
+	})
+}
+
+// sendResult 結果がまだ送られていなければ送る (ブラウザの再読み込みなどで2回目以降に呼ばれても待たない)
+func sendResult(results chan<- authResult, result authResult) {
+	select {
+	case results <- result:
+	default:
+	}
+}
+
+// scope OAuthの設定のスコープ (トークンの保存先を決めるのに使う)
+func scope(config *oauth2.Config) string {
+	if len(config.Scopes) == 0 {
+		return ReadonlyScope
+	}
+	return config.Scopes[0]
+}
+
+// OpenURL ブラウザでURLを開く
+func OpenURL(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = "cmd"
+		args = []string{"/c", "start"}
+	case "darwin":
+		cmd = "open"
+	case "linux":
+		cmd = "xdg-open"
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+
+	args = append(args, url)
+	return exec.Command(cmd, args...).Start()
+}
@@ -0,0 +1,113 @@
+package gmailapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenServer トークンのエンドポイントの代わりのサーバー
+// code が "valid" なら access_token を返し、それ以外は invalid_grant を返す
+func tokenServer(t *testing.T) *oauth2.Config {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("code") == "valid" {
+			w.Write([]byte(`{"access_token": "access", "token_type": "Bearer", "refresh_token": "refresh", "expires_in": 3600}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return &oauth2.Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+		Scopes:       []string{ReadonlyScope},
+	}
+}
+
+func TestCallbackHandler(t *testing.T) {
+	config := tokenServer(t)
+
+	for _, tt := range []struct {
+		name      string
+		query     string
+		wantToken bool
+		wantCode  int
+	}{
+		{"success", "state=s1&code=valid", true, http.StatusOK},
+		{"state mismatch", "state=other&code=valid", false, http.StatusBadRequest},
+		{"denied", "state=s1&error=access_denied", false, http.StatusForbidden},
+		{"exchange failed", "state=s1&code=invalid", false, http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make(chan authResult, 1)
+			handler := callbackHandler(config, "s1", results)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/callback?"+tt.query, nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			// 結果はちょうど1つ送られ、エラーかトークンのどちらかだけを持つ
+			select {
+			case result := <-results:
+				if tt.wantToken {
+					if result.err != nil || result.token == nil || result.token.AccessToken != "access" {
+						t.Errorf("result = %+v", result)
+					}
+				} else if result.err == nil || result.token != nil {
+					t.Errorf("result = %+v, want only an error", result)
+				}
+			default:
+				t.Fatal("no result")
+			}
+
+			// 2回目のコールバックでは待たずに結果を捨てる
+			done := make(chan struct{})
+			go func() {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/callback?"+tt.query, nil))
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/callback?"+tt.query, nil))
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("callback blocked on the second request")
+			}
+		})
+	}
+}
+
+// TestRefreshTokenRevoked 失効したトークンはブラウザで認証し直さずに auth login を案内する
+func TestRefreshTokenRevoked(t *testing.T) {
+	config := tokenServer(t)
+	token := &oauth2.Token{AccessToken: "old", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Minute)}
+
+	_, err := RefreshToken(context.Background(), "work", config, token)
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("err = %v, want %v", err, ErrTokenRevoked)
+	}
+	if !strings.Contains(err.Error(), "auth login") || !strings.Contains(err.Error(), "--account work") {
+		t.Errorf("err = %v, want the auth login command", err)
+	}
+}
+
+func TestLoginCommand(t *testing.T) {
+	if got := loginCommand("work", ModifyScope); !strings.HasSuffix(got, "--account work --scope modify") {
+		t.Errorf("loginCommand = %q", got)
+	}
+	if got := loginCommand("work", ReadonlyScope); strings.Contains(got, "--scope") {
+		t.Errorf("loginCommand = %q", got)
+	}
+}
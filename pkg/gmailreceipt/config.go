@@ -0,0 +1,125 @@
+package gmailreceipt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule メールで届く請求書1種類分の設定
+type Rule struct {
+	Name       string `yaml:"name"`                 // 設定の名前 (ファイル名や --rule での指定に使う)
+	Query      string `yaml:"query"`                // Gmailの検索クエリ (例: from:billing@example.com subject:Invoice)
+	Sender     string `yaml:"sender,omitempty"`     // 差出人 (Fromヘッダーに含まれる文字列)
+	Attachment string `yaml:"attachment,omitempty"` // 保存する添付ファイル名の正規表現 (デフォルト: PDF全て)
+	Vendor     string `yaml:"vendor,omitempty"`     // 請求書の発行者
+
+	// 件名と本文のテキストからメタデータを取り出す正規表現 (1つ目のグループの値を使う)
+	Extract struct {
+		ID    string `yaml:"id,omitempty"`    // 請求書番号 (デフォルト: メールのID)
+		Date  string `yaml:"date,omitempty"`  // 請求日 (デフォルト: メールの受信日)
+		Total string `yaml:"total,omitempty"` // 合計金額
+		Items string `yaml:"items,omitempty"` // 商品名 (一致した全てを使う)
+	} `yaml:"extract,omitempty"`
+
+	attachmentRe *regexp.Regexp
+	idRe         *regexp.Regexp
+	dateRe       *regexp.Regexp
+	totalRe      *regexp.Regexp
+	itemsRe      *regexp.Regexp
+}
+
+// Config Gmailの請求書取得の設定ファイル
+type Config struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// defaultAttachment 添付ファイル名の指定がない場合はPDFを全て保存する
+const defaultAttachment = `(?i)\.pdf$`
+
+// ConfigPath 設定ファイルのデフォルトのパスを取得する
+func ConfigPath() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDirPath, "gmail.yaml"), nil
+}
+
+// LoadConfig 設定ファイルを読み込む (path が空の場合はデフォルトのパス)
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		defaultPath, err := ConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("Gmailの設定ファイルの読み込みに失敗しました %s: %w", path, err)
+	}
+
+	for i, r := range config.Rules {
+		if r.Name == "" || r.Query == "" {
+			return nil, fmt.Errorf("rules[%d]: name と query は必須です", i)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Find 名前で設定を探す
+func (c *Config) Find(name string) (*Rule, error) {
+	for _, r := range c.Rules {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("Gmailの設定が見つかりません: %s", name)
+}
+
+// compile 正規表現を事前に解釈しておく
+func (r *Rule) compile() error {
+	attachment := r.Attachment
+	if attachment == "" {
+		attachment = defaultAttachment
+	}
+
+	for _, c := range []struct {
+		name    string
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{"attachment", attachment, &r.attachmentRe},
+		{"extract.id", r.Extract.ID, &r.idRe},
+		{"extract.date", r.Extract.Date, &r.dateRe},
+		{"extract.total", r.Extract.Total, &r.totalRe},
+		{"extract.items", r.Extract.Items, &r.itemsRe},
+	} {
+		if c.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(c.pattern)
+		if err != nil {
+			return fmt.Errorf("%s の正規表現が不正です: %w", c.name, err)
+		}
+		*c.re = re
+	}
+
+	return nil
+}
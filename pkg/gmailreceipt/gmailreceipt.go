@@ -0,0 +1,218 @@
+package gmailreceipt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/extract"
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"github.com/JINZO631/freeedom/pkg/invoice"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/fatih/color"
+	"google.golang.org/api/gmail/v1"
+)
+
+// Provider 領収書の取得元の名前
+const Provider = "gmail"
+
+// Run 設定ファイルのルールごとにGmailを検索し、添付されている請求書を保存する
+// ruleNames が空の場合は全てのルールを対象にする
+// credentials: 対象にするGmailのアカウントの認証情報
+// labels: 処理したメールへのラベルの付与と、処理済みのメールを検索から除くかどうか
+func Run(ctx context.Context, credentials *gmailapi.Credentials, configPath string, ruleNames []string, labels gmailapi.LabelOptions, afterDate, beforeDate, outputDir string) error {
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	rules := config.Rules
+	if len(ruleNames) > 0 {
+		rules = []*Rule{}
+		for _, name := range ruleNames {
+			r, err := config.Find(name)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, r)
+		}
+	}
+
+	// Gmailサービスを作成
+	accounts, err := credentials.Connect(ctx, labels.Scope())
+	if err != nil {
+		return err
+	}
+
+	report := receipt.NewReport(Provider, afterDate, beforeDate)
+	for _, account := range accounts {
+		if err := runAccount(account, rules, labels, afterDate, beforeDate, outputDir, report); err != nil {
+			return fmt.Errorf("アカウント %s: %w", account.Name, err)
+		}
+	}
+
+	reportPath, err := report.Write(outputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Println("レポートを保存しました:", reportPath)
+
+	return nil
+}
+
+// runAccount 1つのアカウントのGmailをルールごとに検索し、保存した領収書をレポートに追加する
+func runAccount(account *gmailapi.Account, rules []*Rule, labels gmailapi.LabelOptions, afterDate, beforeDate, outputDir string, report *receipt.Report) error {
+	fmt.Printf("Gmailのアカウント: %s (%s)\n", account.Email, account.Name)
+	gmailService := account.Service
+	labeler := gmailapi.NewLabeler(gmailService, labels)
+
+	for _, rule := range rules {
+		query := labels.Query(fmt.Sprintf("after:%s before:%s has:attachment %s", afterDate, beforeDate, rule.Query))
+		fmt.Println(rule.Name, "のメールを探します。 query: ", query)
+
+		mails, err := gmailapi.GetEmails(gmailService, query)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("メールを取得しました。 取得数: ", len(mails))
+		for _, mail := range mails {
+			r, err := SaveReceipt(gmailService, rule, mail, account.Email, outputDir)
+			if err != nil {
+				fmt.Println(color.RedString("×"), rule.Name, mail.Id, err)
+				if err := labeler.Mark(mail.Id, false); err != nil {
+					return err
+				}
+				continue
+			}
+			if r == nil {
+				// 差出人や添付ファイル名が条件に合わないメール
+				continue
+			}
+			report.Add(r)
+			if err := labeler.Mark(mail.Id, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SaveReceipt メールの添付ファイルとメタデータを保存する
+// account はメールを取得したアカウントのメールアドレスで、メタデータに記録する
+// 差出人や添付ファイル名が設定に合わない場合は nil を返す
+func SaveReceipt(srv *gmail.Service, rule *Rule, mail *gmail.Message, account, outputDir string) (*receipt.Receipt, error) {
+	if rule.Sender != "" && !strings.Contains(gmailapi.Header(mail, "From"), rule.Sender) {
+		return nil, nil
+	}
+
+	parts := []*gmail.MessagePart{}
+	for _, p := range gmailapi.Attachments(mail) {
+		if rule.attachmentRe.MatchString(p.Filename) {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	text, err := messageText(mail)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &receipt.Receipt{
+		Provider: Provider,
+		ID:       mail.Id,
+		Vendor:   rule.Vendor,
+		Date:     time.UnixMilli(mail.InternalDate).Format("2006-01-02"),
+		Account:  account,
+	}
+	if id := submatch(rule.idRe, text); id != "" {
+		r.ID = id
+	}
+	if date := receipt.NormalizeDate(submatch(rule.dateRe, text)); date != "" {
+		r.Date = date
+	}
+	// 外貨建ての領収書もあるので通貨も読み取る
+	r.Total, r.Currency = receipt.ParseMoney(submatch(rule.totalRe, text))
+	if rule.itemsRe != nil {
+		for _, m := range rule.itemsRe.FindAllStringSubmatch(text, -1) {
+			if len(m) > 1 {
+				r.Items = append(r.Items, strings.TrimSpace(m[1]))
+			}
+		}
+	}
+
+	// 適格請求書発行事業者の登録番号をメール本文から探す
+	if number, err := invoice.Extract(text); err == nil {
+		r.RegistrationNumber = number
+	}
+
+	// 1つ目の添付ファイルを領収書、残りを添付資料として保存する
+	var pdfPath string
+	for i, p := range parts {
+		data, err := gmailapi.AttachmentData(srv, mail.Id, p)
+		if err != nil {
+			return nil, err
+		}
+
+		fileName := fmt.Sprintf("%s_%s_%d_%s", rule.Name, mail.Id, i+1, filepath.Base(p.Filename))
+		path, err := receipt.WritePDF(outputDir, fileName, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			pdfPath = path
+			r.PDFFile = fileName
+		} else {
+			r.Attachments = append(r.Attachments, fileName)
+		}
+	}
+
+	if _, err := receipt.WriteSidecar(pdfPath, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// messageText 件名と本文をメタデータの抽出に使うテキストにする
+// テキストの本文があればそれを、なければHTMLの本文からテキストを取り出して使う
+func messageText(mail *gmail.Message) (string, error) {
+	body, err := gmailapi.Body(mail, "text/plain")
+	if err != nil {
+		return "", err
+	}
+
+	if body == "" {
+		htmlBody, err := gmailapi.Body(mail, "text/html")
+		if err != nil {
+			return "", err
+		}
+		doc, err := extract.Parse(htmlBody)
+		if err != nil {
+			return "", err
+		}
+		body = strings.Join(extract.Lines(doc), "\n")
+	}
+
+	return gmailapi.Header(mail, "Subject") + "\n" + body, nil
+}
+
+// submatch 正規表現の1つ目のグループに一致した値を返す
+func submatch(re *regexp.Regexp, text string) string {
+	if re == nil {
+		return ""
+	}
+	m := re.FindStringSubmatch(text)
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
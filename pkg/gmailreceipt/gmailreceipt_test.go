@@ -0,0 +1,211 @@
+package gmailreceipt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// fakeGmail テスト用のGmailAPIのサーバー
+type fakeGmail struct {
+	messages    []*gmail.Message
+	attachments map[string]string // 添付ファイルのIDと中身 (ない場合は404を返す)
+	labels      []*gmail.Label
+	modified    map[string]*gmail.ModifyMessageRequest
+}
+
+func (f *fakeGmail) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me/"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "messages":
+		res := &gmail.ListMessagesResponse{}
+		for _, m := range f.messages {
+			res.Messages = append(res.Messages, &gmail.Message{Id: m.Id})
+		}
+		writeJSON(w, res)
+	case len(path) == 2 && path[0] == "messages":
+		for _, m := range f.messages {
+			if m.Id == path[1] {
+				writeJSON(w, m)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case len(path) == 4 && path[0] == "messages" && path[2] == "attachments":
+		data, ok := f.attachments[path[3]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, &gmail.MessagePartBody{Data: encode(data)})
+	case len(path) == 3 && path[0] == "messages" && path[2] == "modify":
+		req := &gmail.ModifyMessageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.modified[path[1]] = req
+		writeJSON(w, &gmail.Message{Id: path[1]})
+	case len(path) == 1 && path[0] == "labels" && r.Method == http.MethodPost:
+		label := &gmail.Label{}
+		if err := json.NewDecoder(r.Body).Decode(label); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		label.Id = "created-" + label.Name
+		f.labels = append(f.labels, label)
+		writeJSON(w, label)
+	case len(path) == 1 && path[0] == "labels":
+		writeJSON(w, &gmail.ListLabelsResponse{Labels: f.labels})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRunAccount(t *testing.T) {
+	fake := &fakeGmail{
+		messages: []*gmail.Message{
+			// 添付ファイル名にディレクトリが含まれていても出力先ディレクトリに保存する
+			message("m1", "請求書のお知らせ", "合計 ¥1,100\n登録番号 T9234567890123",
+				attachment("invoice.pdf", "", "%PDF-invoice"),
+				attachment("../../receipts/明細.pdf", "a1", ""),
+				attachment("readme.txt", "", "text"),
+			),
+			// 添付ファイルを取得できないメールは失敗のラベルを付ける
+			message("m2", "請求書のお知らせ", "合計 ¥2,200", attachment("invoice.pdf", "missing", "")),
+			// 添付ファイルがないメールは保存もラベルの付与もしない
+			message("m3", "お知らせ", "添付ファイルはありません"),
+		},
+		attachments: map[string]string{"a1": "%PDF-detail"},
+		labels:      []*gmail.Label{{Id: "L1", Name: gmailapi.LabelProcessed}},
+		modified:    map[string]*gmail.ModifyMessageRequest{},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	srv, err := gmail.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := &Rule{Name: "billing", Query: "from:billing@example.com", Vendor: "Example"}
+	rule.Extract.Total = `合計\s*(\S+)`
+	if err := rule.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	report := receipt.NewReport(Provider, "2024-01-01", "2024-02-01")
+	account := &gmailapi.Account{Name: "default", Email: "me@example.com", Service: srv}
+	if err := runAccount(account, []*Rule{rule}, gmailapi.LabelOptions{Mark: true}, "2024-01-01", "2024-02-01", outputDir, report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Receipts) != 1 {
+		t.Fatalf("report has %d receipts, want 1", len(report.Receipts))
+	}
+	got := report.Receipts[0]
+	want := &receipt.Receipt{
+		Provider:           Provider,
+		ID:                 "m1",
+		Vendor:             "Example",
+		Date:               got.Date,
+		Total:              1100,
+		PDFFile:            "billing_m1_1_invoice.pdf",
+		Attachments:        []string{"billing_m1_2_明細.pdf"},
+		Account:            "me@example.com",
+		RegistrationNumber: "T9234567890123",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("receipt = %+v\nwant %+v", got, want)
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	wantFiles := []string{"billing_m1_1_invoice.json", "billing_m1_1_invoice.pdf", "billing_m1_2_明細.pdf"}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %v, want %v", files, wantFiles)
+	}
+	if b, err := os.ReadFile(filepath.Join(outputDir, "billing_m1_2_明細.pdf")); err != nil || string(b) != "%PDF-detail" {
+		t.Errorf("attachment = %q, %v", b, err)
+	}
+
+	failed := "created-" + gmailapi.LabelFailed
+	wantModified := map[string]*gmail.ModifyMessageRequest{
+		"m1": {AddLabelIds: []string{"L1"}, RemoveLabelIds: []string{failed}},
+		"m2": {AddLabelIds: []string{failed}, RemoveLabelIds: []string{"L1"}},
+	}
+	if !reflect.DeepEqual(fake.modified, wantModified) {
+		t.Errorf("modified labels = %v, want %v", fake.modified, wantModified)
+	}
+}
+
+func TestMessageTextHTML(t *testing.T) {
+	mail := &gmail.Message{Payload: &gmail.MessagePart{
+		Headers: []*gmail.MessagePartHeader{{Name: "Subject", Value: "領収書"}},
+		Parts: []*gmail.MessagePart{
+			{MimeType: "text/html", Body: &gmail.MessagePartBody{Data: encode("<p>合計</p><p>¥500</p>")}},
+		},
+	}}
+	got, err := messageText(mail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "領収書\n合計\n¥500"; got != want {
+		t.Errorf("messageText = %q, want %q", got, want)
+	}
+}
+
+// message テキストの本文と添付ファイルのメールを作成する
+func message(id, subject, body string, attachments ...*gmail.MessagePart) *gmail.Message {
+	parts := []*gmail.MessagePart{
+		{MimeType: "text/plain", Body: &gmail.MessagePartBody{Data: encode(body)}},
+	}
+	return &gmail.Message{
+		Id: id,
+		Payload: &gmail.MessagePart{
+			MimeType: "multipart/mixed",
+			Headers: []*gmail.MessagePartHeader{
+				{Name: "From", Value: "billing@example.com"},
+				{Name: "Subject", Value: subject},
+			},
+			Parts: append(parts, attachments...),
+		},
+	}
+}
+
+// attachment 添付ファイルのパートを作成する (attachmentID が空の場合は中身をメールに含める)
+func attachment(filename, attachmentID, data string) *gmail.MessagePart {
+	body := &gmail.MessagePartBody{AttachmentId: attachmentID}
+	if attachmentID == "" {
+		body.Data = encode(data)
+	}
+	return &gmail.MessagePart{MimeType: "application/octet-stream", Filename: filename, Body: body}
+}
+
+func encode(s string) string {
+	return base64.URLEncoding.EncodeToString([]byte(s))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
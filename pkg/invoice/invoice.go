@@ -0,0 +1,89 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// ErrNotFound 本文に登録番号が見つからなかった場合のエラー
+var ErrNotFound = errors.New("適格請求書発行事業者の登録番号が見つかりません")
+
+// separators 登録番号の区切り文字 (空白とハイフン。全角の文字は width.Fold で半角にしてから探す)
+// 領収書ではハイフンの代わりにマイナス記号 (−)・ダッシュ・長音記号 (ー) が使われていることもある
+const separators = `[\s\-‐‑‒–—―−ー]`
+
+// numberRe T + 13桁の登録番号を探す正規表現
+// 領収書によっては T-1234-5678-9012-3 のように区切られていることがあるので区切り文字も許容する
+var numberRe = regexp.MustCompile(`T` + separators + `?((?:\d` + separators + `?){12}\d)`)
+
+// separatorRe 登録番号の区切り文字
+var separatorRe = regexp.MustCompile(separators)
+
+// Find テキストに含まれる登録番号の候補を全て探す (チェックデジットは検証しない)
+func Find(text string) []string {
+	// 全角の英数字を半角にしてから探す
+	text = width.Fold.String(text)
+
+	numbers := []string{}
+	seen := map[string]bool{}
+	for _, m := range numberRe.FindAllStringSubmatch(text, -1) {
+		number := "T" + separatorRe.ReplaceAllString(m[1], "")
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+// Extract テキストからチェックデジットが正しい最初の登録番号を取り出す
+func Extract(text string) (string, error) {
+	for _, number := range Find(text) {
+		if Validate(number) == nil {
+			return number, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Validate 登録番号の形式とチェックデジットを検証する
+// 登録番号は T + 13桁の数字で、先頭の1桁は法人番号と同じ方式で計算したチェックデジット
+func Validate(number string) error {
+	digits, ok := strings.CutPrefix(number, "T")
+	if !ok {
+		return fmt.Errorf("登録番号はTで始まる必要があります: %s", number)
+	}
+	if len(digits) != 13 {
+		return fmt.Errorf("登録番号はTと13桁の数字である必要があります: %s", number)
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("登録番号に数字以外が含まれています: %s", number)
+		}
+	}
+
+	if checkDigit(digits[1:]) != int(digits[0]-'0') {
+		return fmt.Errorf("登録番号のチェックデジットが一致しません: %s", number)
+	}
+	return nil
+}
+
+// checkDigit 12桁の基礎番号からチェックデジットを計算する
+// 9 - (Σ 下からn桁目の数字 × (nが奇数なら1、偶数なら2) を9で割った余り)
+func checkDigit(base string) int {
+	sum := 0
+	for n := 1; n <= len(base); n++ {
+		p := int(base[len(base)-n] - '0')
+		q := 1
+		if n%2 == 0 {
+			q = 2
+		}
+		sum += p * q
+	}
+	return 9 - sum%9
+}
@@ -0,0 +1,55 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want []string
+	}{
+		{"登録番号: T9234567890123", []string{"T9234567890123"}},
+		{"登録番号 T-9234-5678-9012-3", []string{"T9234567890123"}},
+		{"登録番号 T 9234 5678 9012 3", []string{"T9234567890123"}},
+		{"登録番号：Ｔ９２３４５６７８９０１２３", []string{"T9234567890123"}},
+		{"登録番号：Ｔ－９２３４－５６７８－９０１２－３", []string{"T9234567890123"}},
+		{"登録番号 T−9234−5678−9012−3", []string{"T9234567890123"}},
+		{"登録番号 T‐9234‐5678‐9012‐3", []string{"T9234567890123"}},
+		{"登録番号 Tー9234ー5678ー9012ー3", []string{"T9234567890123"}},
+		{"登録番号 T　9234　5678　9012　3", []string{"T9234567890123"}},
+		{"T9234567890123 と T9234567890123", []string{"T9234567890123"}},
+		{"T923456789012", []string{}},
+		{"", []string{}},
+	} {
+		if got := Find(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	got, err := Extract("T1234567890123 T9234567890123")
+	if err != nil || got != "T9234567890123" {
+		t.Errorf("Extract = %q, %v", got, err)
+	}
+	if _, err := Extract("T1234567890123"); err != ErrNotFound {
+		t.Errorf("Extract: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for number, ok := range map[string]bool{
+		"T9234567890123":  true,
+		"T1234567890123":  false,
+		"9234567890123":   false,
+		"T923456789012":   false,
+		"T923456789012a":  false,
+		"T92345678901234": false,
+	} {
+		if err := Validate(number); (err == nil) != ok {
+			t.Errorf("Validate(%q) = %v", number, err)
+		}
+	}
+}
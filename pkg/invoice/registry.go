@@ -0,0 +1,297 @@
+package invoice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// ErrRegistryNotImported 公表データがインポートされていない場合のエラー
+var ErrRegistryNotImported = errors.New("適格請求書発行事業者の公表データがインポートされていません。 freeedom invoice import で取り込んでください")
+
+// Registrant 適格請求書発行事業者の公表情報
+type Registrant struct {
+	Number           string `json:"number"`                  // 登録番号
+	Name             string `json:"name"`                    // 氏名又は名称
+	Kind             string `json:"kind"`                    // 人格区分 (1: 個人, 2: 法人)
+	RegistrationDate string `json:"registration_date"`       // 登録年月日
+	DisposalDate     string `json:"disposal_date,omitempty"` // 取消年月日
+	ExpireDate       string `json:"expire_date,omitempty"`   // 失効年月日
+	TradeName        string `json:"trade_name,omitempty"`    // 屋号
+	PopularName      string `json:"popular_name,omitempty"`  // 通称・旧姓
+}
+
+// Active 登録が取消・失効していないか
+func (r *Registrant) Active() bool {
+	return r.DisposalDate == "" && r.ExpireDate == ""
+}
+
+// 国税庁の公表データ (CSV形式) の列番号
+const (
+	columnNumber           = 1
+	columnKind             = 4
+	columnLatest           = 6
+	columnRegistrationDate = 7
+	columnDisposalDate     = 9
+	columnExpireDate       = 10
+	columnName             = 18
+	columnTradeName        = 22
+	columnPopularName      = 23
+)
+
+// RegistryPath インポートした公表データの保存先を取得する
+func RegistryPath() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDirPath, "invoice", "registrants.csv"), nil
+}
+
+// Import 国税庁の適格請求書発行事業者公表システムからダウンロードした全件・差分データ(CSV)を取り込む
+// 最新の履歴の行だけを必要な列に絞って保存する。複数ファイルに分かれている場合は順に取り込む
+// 取り込み済みの公表データは消さず、同じ登録番号の行だけを新しいデータで置き換える (後に指定したファイルを優先する)
+func Import(srcPaths []string) (int, error) {
+	registryPath, err := RegistryPath()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(registryPath), 0o755); err != nil {
+		return 0, err
+	}
+
+	// 途中で失敗しても取り込み済みのデータが壊れないように、一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(registryPath), ".registrants.csv.tmp*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := csv.NewWriter(tmp)
+	imported := map[uint64]bool{}
+	count := 0
+	for i := len(srcPaths) - 1; i >= 0; i-- {
+		n, err := importFile(w, srcPaths[i], imported)
+		if err != nil {
+			return 0, fmt.Errorf("公表データの取り込みに失敗しました %s: %w", srcPaths[i], err)
+		}
+		count += n
+	}
+
+	if err := copyRegistry(w, registryPath, imported); err != nil {
+		return 0, fmt.Errorf("取り込み済みの公表データの読み込みに失敗しました %s: %w", registryPath, err)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), registryPath); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// registryKey 登録番号を重複の確認に使う数値にする
+// 公表データは数百万件あるため、文字列ではなく数値で覚えてメモリを節約する
+func registryKey(number string) (uint64, bool) {
+	digits, ok := strings.CutPrefix(number, "T")
+	if !ok {
+		return 0, false
+	}
+	key, err := strconv.ParseUint(digits, 10, 64)
+	return key, err == nil
+}
+
+// copyRegistry 取り込み済みの公表データのうち、今回取り込んでいない登録番号の行を書き写す
+func copyRegistry(w *csv.Writer, registryPath string, imported map[uint64]bool) error {
+	f, err := os.Open(registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 8
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if key, ok := registryKey(record[0]); ok && imported[key] {
+			continue
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+}
+
+// importFile 公表データのファイルから最新の履歴の行を書き出す
+// imported に含まれる (後のファイルで取り込んだ) 登録番号の行は書き出さない
+func importFile(w *csv.Writer, srcPath string, imported map[uint64]bool) (int, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	r, err := decodeReader(src)
+	if err != nil {
+		return 0, err
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	count := 0
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if len(record) <= columnName {
+			continue
+		}
+
+		// 変更履歴のうち最新のものだけを使う
+		if record[columnLatest] != "1" {
+			continue
+		}
+
+		if key, ok := registryKey(record[columnNumber]); ok {
+			if imported[key] {
+				continue
+			}
+			imported[key] = true
+		}
+
+		if err := w.Write([]string{
+			record[columnNumber],
+			record[columnName],
+			record[columnKind],
+			record[columnRegistrationDate],
+			record[columnDisposalDate],
+			record[columnExpireDate],
+			column(record, columnTradeName),
+			column(record, columnPopularName),
+		}); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// decodeReader UTF-8(BOM付きを含む)とShift_JISのどちらの公表データでも読めるようにする
+func decodeReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, 4096)
+	head, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(head, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+		return br, nil
+	}
+
+	// 先頭部分がUTF-8として不正ならShift_JISとして扱う (末尾で途切れたマルチバイト文字は無視する)
+	for i := 0; i < utf8.UTFMax-1 && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return transform.NewReader(br, japanese.ShiftJIS.NewDecoder()), nil
+	}
+
+	return br, nil
+}
+
+func column(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+// Lookup インポート済みの公表データから登録番号を探す
+// 見つからなかった番号は結果のmapに含まれない
+func Lookup(numbers []string) (map[string]*Registrant, error) {
+	registryPath, err := RegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrRegistryNotImported
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	wanted := map[string]bool{}
+	for _, number := range numbers {
+		wanted[number] = true
+	}
+
+	// 公表データは数百万件あるため全てをメモリに載せず、必要な番号だけを拾う
+	registrants := map[string]*Registrant{}
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = 8
+	for len(registrants) < len(wanted) {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !wanted[record[0]] {
+			continue
+		}
+
+		registrants[record[0]] = &Registrant{
+			Number:           record[0],
+			Name:             record[1],
+			Kind:             record[2],
+			RegistrationDate: record[3],
+			DisposalDate:     record[4],
+			ExpireDate:       record[5],
+			TradeName:        record[6],
+			PopularName:      record[7],
+		}
+	}
+
+	return registrants, nil
+}
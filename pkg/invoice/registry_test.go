@@ -0,0 +1,120 @@
+package invoice
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// publication 国税庁の公表データ (CSV形式) の1行を作る
+func publication(number, latest, name, disposalDate string) string {
+	record := make([]string, 30)
+	record[0] = "1"
+	record[columnNumber] = number
+	record[columnKind] = "2"
+	record[columnLatest] = latest
+	record[columnRegistrationDate] = "2023-10-01"
+	record[columnDisposalDate] = disposalDate
+	record[columnName] = name
+	return strings.Join(record, ",") + "\r\n"
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	if _, err := Lookup([]string{"T9234567890123"}); err != ErrRegistryNotImported {
+		t.Fatalf("Lookup before import: err = %v", err)
+	}
+
+	// 全件データ (UTF-8・BOM付き)
+	full := writeFile(t, "zenken.csv", []byte("\xef\xbb\xbf"+
+		publication("T9234567890123", "1", "株式会社テスト", "")+
+		publication("T8000012010001", "0", "旧名称", "")+
+		publication("T8000012010001", "1", "テスト合同会社", "")))
+	count, err := Import([]string{full})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	// 差分データ (Shift_JIS): 1件が取消、1件が新規。全件データの残りの行は消えない
+	diff, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(
+		publication("T8000012010001", "1", "テスト合同会社", "2024-04-01") +
+			publication("T2000012010019", "1", "新規株式会社", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同じ登録番号が複数のファイルにある場合は後のファイルを使う
+	later := writeFile(t, "sabun2.csv", []byte(publication("T2000012010019", "1", "新規株式会社 (名称変更)", "")))
+	count, err = Import([]string{writeFile(t, "sabun1.csv", diff), later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	got, err := Lookup([]string{"T9234567890123", "T8000012010001", "T2000012010019", "T1234567890123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3", len(got))
+	}
+	if r := got["T9234567890123"]; r.Name != "株式会社テスト" || !r.Active() {
+		t.Errorf("T9234567890123 = %+v", r)
+	}
+	if r := got["T8000012010001"]; r.Name != "テスト合同会社" || r.Active() {
+		t.Errorf("T8000012010001 = %+v", r)
+	}
+	if r := got["T2000012010019"]; r.Name != "新規株式会社 (名称変更)" {
+		t.Errorf("T2000012010019 = %+v", r)
+	}
+
+	// 登録番号ごとに1行だけ残る
+	registryPath, err := RegistryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(registryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 3 {
+		t.Errorf("registry has %d rows, want 3:\n%s", n, b)
+	}
+}
+
+func TestImportKeepsRegistryOnError(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	if _, err := Import([]string{writeFile(t, "zenken.csv", []byte(publication("T9234567890123", "1", "株式会社テスト", "")))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import([]string{filepath.Join(t.TempDir(), "missing.csv")}); err == nil {
+		t.Fatal("want error")
+	}
+	got, err := Lookup([]string{"T9234567890123"})
+	if err != nil || got["T9234567890123"] == nil {
+		t.Errorf("Lookup after a failed import = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,83 @@
+package journal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/rules"
+)
+
+// Entry 領収書1件分の仕訳
+type Entry struct {
+	ID          string   // 管理番号 (取得元-領収書ID)
+	Date        string   // 発生日 (format: 2024-01-01)
+	Partner     string   // 取引先
+	AccountItem string   // 勘定科目
+	TaxCategory string   // 税区分
+	Amount      int      // 金額 (税込、家事按分後)
+	Description string   // 備考
+	Wallet      string   // 決済口座
+	Tags        []string // メモタグ
+
+	CreditAccountItem string // 貸方勘定科目
+	CreditSubAccount  string // 貸方補助科目
+
+	BusinessRatio int // 家事按分の事業割合 (%)
+
+	Receipt *receipt.Receipt // 元になった領収書
+}
+
+// NewEntries 領収書にルールを適用して日付順の仕訳を作る
+// 事業割合が0%の (全額が私用の) 領収書と、円に換算できない外貨建ての領収書は仕訳にしない
+func NewEntries(receipts []*receipt.Receipt, rs *rules.RuleSet) ([]*Entry, error) {
+	entries := []*Entry{}
+	for _, r := range receipts {
+		if r.Currency != "" {
+			continue
+		}
+		result, err := rs.Apply(r)
+		if err != nil {
+			return nil, err
+		}
+		if result.BusinessAmount == 0 {
+			continue
+		}
+
+		entries = append(entries, &Entry{
+			ID:            fmt.Sprintf("%s-%s", r.Provider, r.ID),
+			Date:          r.Date,
+			Partner:       result.Partner,
+			AccountItem:   result.AccountItem,
+			TaxCategory:   result.TaxCategory,
+			Amount:        result.BusinessAmount,
+			Description:   result.Memo,
+			Wallet:        result.Wallet,
+			Tags:          result.Tags,
+			BusinessRatio: result.BusinessRatio,
+			Receipt:       r,
+
+			CreditAccountItem: result.CreditAccountItem,
+			CreditSubAccount:  result.CreditSubAccount,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// ForeignReceipts 仕訳にしない外貨建ての領収書を返す
+func ForeignReceipts(receipts []*receipt.Receipt) []*receipt.Receipt {
+	foreign := []*receipt.Receipt{}
+	for _, r := range receipts {
+		if r.Currency != "" {
+			foreign = append(foreign, r)
+		}
+	}
+	return foreign
+}
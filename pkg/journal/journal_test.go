@@ -0,0 +1,38 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/JINZO631/freeedom/pkg/rules"
+)
+
+func TestNewEntriesSkipsForeignCurrency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rules.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipts := []*receipt.Receipt{
+		{Provider: "bookwalker", ID: "1", Date: "2024-01-02", Total: 1500},
+		{Provider: "bookwalker", ID: "2", Date: "2024-01-01", Total: 1234, Currency: "USD"},
+	}
+	entries, err := NewEntries(receipts, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != "bookwalker-1" || entries[0].Amount != 1500 {
+		t.Errorf("NewEntries() = %+v, want only bookwalker-1", entries)
+	}
+
+	foreign := ForeignReceipts(receipts)
+	if len(foreign) != 1 || foreign[0].ID != "2" {
+		t.Errorf("ForeignReceipts() = %+v, want only 2", foreign)
+	}
+}
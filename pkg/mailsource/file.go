@@ -0,0 +1,168 @@
+package mailsource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mbox Google Takeoutなどで書き出したmbox形式のファイルからメールを読む
+type Mbox struct {
+	path string
+}
+
+// NewMbox mboxファイルのメールボックスを作成する
+func NewMbox(path string) *Mbox {
+	return &Mbox{path: path}
+}
+
+// Search mboxファイルを先頭から読み、検索条件に一致するメールを返す
+func (m *Mbox) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	f, err := os.Open(m.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := strings.TrimSuffix(filepath.Base(m.path), filepath.Ext(m.path))
+	messages := []*Message{}
+	index := 0
+	err = readMbox(f, func(raw []byte) error {
+		index++
+		msg, err := ParseMessage(fmt.Sprintf("%s-%d", base, index), bytes.NewReader(raw))
+		if err != nil {
+			// 壊れたメールがあっても残りは読み込む
+			fmt.Printf("メールの読み込みに失敗しました %s #%d: %v\n", m.path, index, err)
+			return nil
+		}
+		if q.match(msg) {
+			messages = append(messages, msg)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// fromLineRe メールの区切りの行 (例: From 1789012345678901234@xxx Sat Mar 09 03:00:00 +0000 2024)
+// 差出人の後に時刻を含む行だけを区切りとして扱う
+var fromLineRe = regexp.MustCompile(`^From \S+ .*\d{1,2}:\d{2}`)
+
+// readMbox "From " で始まる行を区切りとしてメールを1通ずつ handle に渡す
+// 本文の "From " をエスケープしないツールで書き出したmboxもあるので、空行の後にある区切りの形式の行だけを区切りにする
+// mboxrd形式のエスケープ (">From " → "From ") も元に戻す
+func readMbox(r io.Reader, handle func(raw []byte) error) error {
+	br := bufio.NewReader(r)
+	var buf bytes.Buffer
+	started := false
+	blank := true // 直前の行が空行 (ファイルの先頭を含む)
+
+	flush := func() error {
+		if !started || buf.Len() == 0 {
+			return nil
+		}
+		raw := make([]byte, buf.Len())
+		copy(raw, buf.Bytes())
+		buf.Reset()
+		return handle(raw)
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case blank && fromLineRe.Match(line):
+				if err := flush(); err != nil {
+					return err
+				}
+				started = true
+			case started:
+				if unescaped := bytes.TrimLeft(line, ">"); len(unescaped) < len(line) && bytes.HasPrefix(unescaped, []byte("From ")) {
+					line = line[1:]
+				}
+				buf.Write(line)
+			}
+			blank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// EMLDir .eml ファイルを置いたディレクトリからメールを読む
+type EMLDir struct {
+	dir string
+}
+
+// NewEMLDir .eml ファイルのディレクトリのメールボックスを作成する
+func NewEMLDir(dir string) *EMLDir {
+	return &EMLDir{dir: dir}
+}
+
+// Search ディレクトリ以下の .eml ファイルを全て読み、検索条件に一致するメールを返す
+func (e *EMLDir) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	paths := []string{}
+	err := filepath.WalkDir(e.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".eml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	messages := []*Message{}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := parseFile(e.dir, path)
+		if err != nil {
+			fmt.Printf("メールの読み込みに失敗しました %s: %v\n", path, err)
+			continue
+		}
+		if q.match(msg) {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, nil
+}
+
+// parseFile .eml ファイルを読む
+// サブディレクトリの同じ名前のファイルと区別するため、ディレクトリからの相対パスをIDにする (例: sub/shop.eml → sub_shop)
+// IDは保存するファイル名に使うので、パスの区切りは "_" に置き換える
+func parseFile(dir, path string) (*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	id := strings.ReplaceAll(filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))), "/", "_")
+	return ParseMessage(id, f)
+}
@@ -0,0 +1,145 @@
+package mailsource
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMboxSearch(t *testing.T) {
+	m := NewMbox(filepath.Join("testdata", "takeout.mbox"))
+	got, err := m.Search(context.Background(), &Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3", len(got))
+	}
+
+	// multipart/alternative・ISO-2022-JPの件名・quoted-printable・base64
+	uber := got[0]
+	if uber.ID != "takeout-1" {
+		t.Errorf("ID = %q", uber.ID)
+	}
+	if uber.Subject != "Uber Eats のご注文の領収書" {
+		t.Errorf("Subject = %q", uber.Subject)
+	}
+	if uber.From != "Uber Eats <noreply@uber.com>" {
+		t.Errorf("From = %q", uber.From)
+	}
+	if want := time.Date(2024, 3, 9, 3, 0, 0, 0, time.UTC); !uber.Date.Equal(want) {
+		t.Errorf("Date = %v", uber.Date)
+	}
+	if !strings.Contains(uber.HTML, `<a href="https://example.com/r.pdf">PDF をダウンロードする &gt;</a>`) {
+		t.Errorf("HTML = %q", uber.HTML)
+	}
+	if uber.Text != "ご注文ありがとうございます。\n合計 ￥1,234\n" {
+		t.Errorf("Text = %q", uber.Text)
+	}
+
+	// multipart/mixed・Shift_JISの本文・エンコードされた添付ファイル名
+	shop := got[1]
+	if shop.Subject != "領収書 (請求書番号 123)" {
+		t.Errorf("Subject = %q", shop.Subject)
+	}
+	if !strings.HasPrefix(shop.Text, "領収書を添付します。\n") {
+		t.Errorf("Text = %q", shop.Text)
+	}
+	if len(shop.Attachments) != 1 || shop.Attachments[0].Filename != "領収書.pdf" || shop.Attachments[0].ContentType != "application/pdf" || string(shop.Attachments[0].Data) != "%PDF-1.4\n%test\n" {
+		t.Errorf("Attachments = %+v", shop.Attachments)
+	}
+
+	// 本文の "From " で始まる行は区切りにせず、mboxrdのエスケープを元に戻す
+	news := got[2]
+	want := "Hello\n\nFrom here on, we send a monthly summary.\nFrom the archive\n>From the archive, quoted\n\n"
+	if news.Text != want {
+		t.Errorf("Text = %q, want %q", news.Text, want)
+	}
+}
+
+func TestMboxSearchQuery(t *testing.T) {
+	m := NewMbox(filepath.Join("testdata", "takeout.mbox"))
+	got, err := m.Search(context.Background(), &Query{Subject: "領収書", After: "2024-03-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "takeout-2" {
+		t.Errorf("got %d messages", len(got))
+	}
+}
+
+func TestReadMbox(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		mbox string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"no separator", "Subject: a\n\nbody\n", nil},
+		{
+			"escaped",
+			"From a@example.com Sat Mar  9 03:00:00 2024\nSubject: a\n\n>From b\n>>From c\n>Fromd\n",
+			[]string{"Subject: a\n\nFrom b\n>From c\n>Fromd\n"},
+		},
+		{
+			"unescaped body line",
+			"From a@example.com Sat Mar  9 03:00:00 2024\nSubject: a\n\nFrom me, see you tomorrow\nFrom b@example.com Sat Mar  9 03:00:00 2024\n\nFrom b@example.com Sat Mar  9 04:00:00 2024\nSubject: b\n\nbody\n",
+			[]string{
+				"Subject: a\n\nFrom me, see you tomorrow\nFrom b@example.com Sat Mar  9 03:00:00 2024\n\n",
+				"Subject: b\n\nbody\n",
+			},
+		},
+		{
+			"crlf",
+			"From a@example.com Sat Mar  9 03:00:00 2024\r\nSubject: a\r\n\r\nbody\r\n\r\nFrom b@example.com Sat Mar  9 04:00:00 2024\r\nSubject: b\r\n\r\nbody",
+			[]string{"Subject: a\r\n\r\nbody\r\n\r\n", "Subject: b\r\n\r\nbody"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if err := readMbox(strings.NewReader(tt.mbox), func(raw []byte) error {
+				got = append(got, string(raw))
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEMLDirSearch(t *testing.T) {
+	e := NewEMLDir(filepath.Join("testdata", "eml"))
+	got, err := e.Search(context.Background(), &Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// サブディレクトリと大文字の拡張子も読み、.eml 以外は読まない
+	// 別のディレクトリにある同じ名前のファイルは相対パスのIDで区別する
+	var ids []string
+	for _, msg := range got {
+		ids = append(ids, msg.ID)
+	}
+	if strings.Join(ids, ",") != "other_shop,sub_shop,uber" {
+		t.Errorf("IDs = %q", ids)
+	}
+	if len(got) == 3 {
+		if got[0].Subject != "領収書 (請求書番号 456)" || got[1].Subject != "領収書 (請求書番号 123)" {
+			t.Errorf("Subjects = %q, %q", got[0].Subject, got[1].Subject)
+		}
+		if got[2].Subject != "Uber Eats のご注文の領収書" {
+			t.Errorf("Subject = %q", got[2].Subject)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.Search(ctx, &Query{}); err == nil {
+		t.Error("canceled: want error")
+	}
+}
@@ -0,0 +1,256 @@
+package mailsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/gmailapi"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// Gmail GmailAPIでメールを取得する
+type Gmail struct {
+	srv     *gmail.Service
+	account *gmailapi.Account
+	options GmailOptions
+	labeler *gmailapi.Labeler
+
+	// 差分同期の状態
+	nextHistoryID uint64                    // 今回の実行を始めた時点の historyId (Checkpoint で保存する)
+	added         []*gmail.Message          // 前回の実行以降に追加されたメール (ヘッダーのみ)
+	fallback      bool                      // 差分を取得できず全件検索する
+	fetched       map[string]*gmail.Message // 本文まで取得したメール
+}
+
+// GmailOptions Gmailのメールボックスの設定
+type GmailOptions struct {
+	// Labels 処理したメールへのラベルの付与と、処理済みのメールを検索から除くかどうか
+	Labels gmailapi.LabelOptions
+
+	// Incremental 前回の実行以降に追加されたメールだけを Users.History.List で取得する
+	// 前回の実行の記録がない場合や、historyId の有効期限が切れている場合は全件検索する
+	Incremental bool
+
+	// Name 差分同期の状態を保存する名前 (メールを処理するコマンドの名前)
+	Name string
+}
+
+// NewGmail 認証済みのアカウントのGmailのメールボックスを作成する
+// options.Labels.Mark が true の場合は、アカウントをGmailの変更の権限で認証しておく必要がある
+func NewGmail(account *gmailapi.Account, options GmailOptions) *Gmail {
+	return &Gmail{
+		srv:     account.Service,
+		account: account,
+		options: options,
+		labeler: gmailapi.NewLabeler(account.Service, options.Labels),
+		fetched: map[string]*gmail.Message{},
+	}
+}
+
+// NewGmailSource 認証情報の全てのアカウントのGmailを1つのメールボックスとして作成する
+func NewGmailSource(ctx context.Context, credentials *gmailapi.Credentials, options GmailOptions) (Source, error) {
+	accounts, err := credentials.Connect(ctx, options.Labels.Scope())
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []Source{}
+	for _, account := range accounts {
+		fmt.Printf("Gmailのアカウント: %s (%s)\n", account.Email, account.Name)
+		sources = append(sources, NewGmail(account, options))
+	}
+	return Multi(sources...), nil
+}
+
+// Search Gmailの検索クエリに変換してメールを取得する
+// 差分同期の場合は前回の実行以降に追加されたメールから検索条件に一致するものを取得する
+func (g *Gmail) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	if g.options.Incremental {
+		if err := g.loadHistory(); err != nil {
+			return nil, err
+		}
+		if !g.fallback {
+			return g.searchAdded(q)
+		}
+	}
+
+	conditions := []string{}
+	if q.After != "" {
+		conditions = append(conditions, "after:"+q.After)
+	}
+	if q.Before != "" {
+		conditions = append(conditions, "before:"+q.Before)
+	}
+	if q.Subject != "" {
+		conditions = append(conditions, fmt.Sprintf(`subject:"%s"`, q.Subject))
+	}
+	if q.From != "" {
+		conditions = append(conditions, "from:"+q.From)
+	}
+	query := g.options.Labels.Query(strings.Join(conditions, " "))
+	fmt.Println("query: ", query)
+
+	mails, err := gmailapi.GetEmails(g.srv, query)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(mails))
+	for _, mail := range mails {
+		m, err := g.convert(mail)
+		if err != nil {
+			return nil, fmt.Errorf("メールの読み込みに失敗しました %s: %w", mail.Id, err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// loadHistory 前回の実行以降に追加されたメールのヘッダーを取得する (2回目以降は何もしない)
+func (g *Gmail) loadHistory() error {
+	if g.nextHistoryID != 0 {
+		return nil
+	}
+
+	// 今回の実行を始めた時点の historyId を次回の起点にする
+	next, err := gmailapi.CurrentHistoryID(g.srv)
+	if err != nil {
+		return err
+	}
+	g.nextHistoryID = next
+
+	start, err := gmailapi.LoadHistoryID(g.account.Name, g.options.Name)
+	if err != nil {
+		return err
+	}
+	if start == 0 {
+		fmt.Println("前回の同期の記録がないため、期間内のメールを全て検索します。")
+		g.fallback = true
+		return nil
+	}
+
+	ids, err := gmailapi.AddedMessageIDs(g.srv, start)
+	if errors.Is(err, gmailapi.ErrHistoryExpired) {
+		fmt.Println("前回の同期から時間が経っているため、期間内のメールを全て検索します。")
+		g.fallback = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("前回の同期以降に追加されたメール数: ", len(ids))
+	for _, id := range ids {
+		mail, err := g.srv.Users.Messages.Get("me", id).Format("metadata").MetadataHeaders("Subject", "From").Do()
+		if err != nil {
+			var apiErr *googleapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+				// 追加された後に削除されたメール
+				continue
+			}
+			return err
+		}
+		g.added = append(g.added, mail)
+	}
+	return nil
+}
+
+// searchAdded 前回の実行以降に追加されたメールから検索条件に一致するものを本文付きで取得する
+// 次回は今回の実行を始めた時点から差分を取得するので、期間外のメールも取りこぼさないように日付では絞り込まない
+func (g *Gmail) searchAdded(q *Query) ([]*Message, error) {
+	added := *q
+	added.After, added.Before = "", ""
+
+	messages := []*Message{}
+	for _, mail := range g.added {
+		header := &Message{
+			ID:      mail.Id,
+			Subject: gmailapi.Header(mail, "Subject"),
+			From:    gmailapi.Header(mail, "From"),
+			Date:    time.UnixMilli(mail.InternalDate),
+		}
+		if !added.match(header) {
+			continue
+		}
+		if g.options.Labels.SkipProcessed {
+			processed, err := g.labeler.HasLabel(mail, gmailapi.LabelProcessed)
+			if err != nil {
+				return nil, err
+			}
+			if processed {
+				continue
+			}
+		}
+
+		full, ok := g.fetched[mail.Id]
+		if !ok {
+			var err error
+			full, err = g.srv.Users.Messages.Get("me", mail.Id).Do()
+			if err != nil {
+				return nil, err
+			}
+			g.fetched[mail.Id] = full
+		}
+
+		m, err := g.convert(full)
+		if err != nil {
+			return nil, fmt.Errorf("メールの読み込みに失敗しました %s: %w", mail.Id, err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// Checkpoint 差分同期の場合、次回の実行のために今回の実行を始めた時点の historyId を保存する
+func (g *Gmail) Checkpoint(ctx context.Context) error {
+	if !g.options.Incremental || g.nextHistoryID == 0 {
+		return nil
+	}
+	return gmailapi.SaveHistoryID(g.account.Name, g.options.Name, g.nextHistoryID)
+}
+
+// Mark 処理したメールに freeedom/processed か freeedom/failed のラベルを付ける (ラベルを付ける設定の場合のみ)
+func (g *Gmail) Mark(ctx context.Context, key MessageKey, processed bool) error {
+	return g.labeler.Mark(key.ID, processed)
+}
+
+// convert GmailAPIのメッセージを変換する
+func (g *Gmail) convert(mail *gmail.Message) (*Message, error) {
+	htmlBody, err := gmailapi.Body(mail, "text/html")
+	if err != nil {
+		return nil, err
+	}
+	textBody, err := gmailapi.Body(mail, "text/plain")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		ID:      mail.Id,
+		Subject: gmailapi.Header(mail, "Subject"),
+		From:    gmailapi.Header(mail, "From"),
+		Date:    time.UnixMilli(mail.InternalDate),
+		HTML:    htmlBody,
+		Text:    textBody,
+		Account: g.account.Email,
+	}
+
+	for _, part := range gmailapi.Attachments(mail) {
+		data, err := gmailapi.AttachmentData(g.srv, mail.Id, part)
+		if err != nil {
+			return nil, err
+		}
+		m.Attachments = append(m.Attachments, &Attachment{
+			Filename:    part.Filename,
+			ContentType: part.MimeType,
+			Data:        data,
+		})
+	}
+
+	return m, nil
+}
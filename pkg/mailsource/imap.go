@@ -0,0 +1,189 @@
+package mailsource
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/schollz/progressbar/v3"
+)
+
+// IMAPConfig IMAPサーバーへの接続設定
+type IMAPConfig struct {
+	Addr     string // host:port (例: imap.gmail.com:993)
+	Username string
+	Password string // Gmailなど2段階認証を使うサーバーではアプリパスワード
+	Mailbox  string // 検索するメールボックス (デフォルト: INBOX)
+	TLS      bool   // 接続時からTLSを使う (993番ポート)。false の場合はSTARTTLSを使う
+	Insecure bool   // TLSもSTARTTLSも使えないサーバーに平文でログインする (パスワードが暗号化されずに送られる)
+}
+
+// IMAP IMAPサーバーからメールを取得する
+type IMAP struct {
+	config IMAPConfig
+}
+
+// NewIMAP IMAPのメールボックスを作成する
+func NewIMAP(config IMAPConfig) *IMAP {
+	if config.Mailbox == "" {
+		config.Mailbox = "INBOX"
+	}
+	return &IMAP{config: config}
+}
+
+// dial IMAPサーバーに接続してログインする
+// ctx がキャンセルされたら接続を閉じて、応答を待っているコマンド (挨拶の受信を含む) を終わらせる
+// 返す関数でログアウトして接続を閉じる
+func (m *IMAP) dial(ctx context.Context) (*client.Client, func(), error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.config.Addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("IMAPサーバーへの接続に失敗しました %s: %w", m.config.Addr, contextError(ctx, err))
+	}
+	if m.config.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: hostname(m.config.Addr)})
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	c, err := client.New(conn)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, fmt.Errorf("IMAPサーバーへの接続に失敗しました %s: %w", m.config.Addr, contextError(ctx, err))
+	}
+	closeFunc := func() {
+		c.Logout()
+		stop()
+	}
+
+	if !m.config.TLS {
+		ok, err := c.SupportStartTLS()
+		if err != nil {
+			closeFunc()
+			return nil, nil, fmt.Errorf("IMAPサーバーへの接続に失敗しました %s: %w", m.config.Addr, contextError(ctx, err))
+		}
+		switch {
+		case ok:
+			if err := c.StartTLS(&tls.Config{ServerName: hostname(m.config.Addr)}); err != nil {
+				closeFunc()
+				return nil, nil, fmt.Errorf("IMAPサーバーへの接続に失敗しました %s: %w", m.config.Addr, contextError(ctx, err))
+			}
+		case !m.config.Insecure:
+			// パスワードを平文で送らないように、明示的に許可された場合だけ暗号化せずにログインする
+			closeFunc()
+			return nil, nil, fmt.Errorf("IMAPサーバー %s はTLSにもSTARTTLSにも対応していないため、パスワードが平文で送られます (許可する場合は --imap-insecure を指定してください)", m.config.Addr)
+		}
+	}
+
+	if err := c.Login(m.config.Username, m.config.Password); err != nil {
+		closeFunc()
+		return nil, nil, fmt.Errorf("IMAPサーバーへのログインに失敗しました: %w", contextError(ctx, err))
+	}
+	return c, closeFunc, nil
+}
+
+// contextError ctx がキャンセルされて接続を閉じたことによるエラーを ctx のエラーに置き換える
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Search サーバー側のSEARCHで件名・日付を絞り込み、一致したメールを取得する
+func (m *IMAP) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	c, closeFunc, err := m.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	if _, err := c.Select(m.config.Mailbox, true); err != nil {
+		return nil, fmt.Errorf("メールボックスを開けません %s: %w", m.config.Mailbox, contextError(ctx, err))
+	}
+
+	criteria := imap.NewSearchCriteria()
+	if q.Subject != "" {
+		criteria.Header.Add("Subject", q.Subject)
+	}
+	if q.From != "" {
+		criteria.Header.Add("From", q.From)
+	}
+	if q.After != "" {
+		after, err := time.Parse("2006-01-02", q.After)
+		if err != nil {
+			return nil, err
+		}
+		criteria.Since = after
+	}
+	if q.Before != "" {
+		before, err := time.Parse("2006-01-02", q.Before)
+		if err != nil {
+			return nil, err
+		}
+		criteria.Before = before
+	}
+
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("メールの検索に失敗しました: %w", contextError(ctx, err))
+	}
+	fmt.Printf("IMAP %s %s: %d件\n", m.config.Addr, m.config.Mailbox, len(uids))
+	if len(uids) == 0 {
+		return []*Message{}, nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchUid, imap.FetchInternalDate, section.FetchItem()}
+
+	fetched := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqSet, items, fetched)
+	}()
+
+	messages := []*Message{}
+	bar := progressbar.Default(int64(len(uids)))
+	var parseErr error
+	for msg := range fetched {
+		body := msg.GetBody(section)
+		if body == nil || parseErr != nil {
+			continue
+		}
+
+		parsed, err := ParseMessage(strconv.FormatUint(uint64(msg.Uid), 10), body)
+		if err != nil {
+			parseErr = fmt.Errorf("メールの読み込みに失敗しました UID %d: %w", msg.Uid, err)
+			continue
+		}
+		if parsed.Date.IsZero() {
+			parsed.Date = msg.InternalDate
+		}
+		messages = append(messages, parsed)
+		bar.Add(1)
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("メールの取得に失敗しました: %w", contextError(ctx, err))
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return messages, nil
+}
+
+// hostname host:port からホスト名を取り出す
+func hostname(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
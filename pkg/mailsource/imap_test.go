@@ -0,0 +1,140 @@
+package mailsource
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+// serveIMAP テスト用のIMAPサーバー (go-imap のメモリ上のバックエンド) を起動し、受信箱にメールを追加する
+// ユーザー名は username、パスワードは password
+func serveIMAP(t *testing.T, mails ...string) string {
+	t.Helper()
+
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox, err := user.GetMailbox("INBOX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mail := range mails {
+		msg, err := ParseMessage("", strings.NewReader(mail))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inbox.CreateMessage(nil, msg.Date, strings.NewReader(mail)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(be)
+	s.AllowInsecureAuth = true
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func mailText(subject, date, body string) string {
+	return strings.Join([]string{
+		"From: Uber Receipts <noreply@uber.com>",
+		"To: taro@example.com",
+		"Subject: " + subject,
+		"Date: " + date,
+		"MIME-Version: 1.0",
+		`Content-Type: text/html; charset="UTF-8"`,
+		"",
+		body,
+	}, "\r\n")
+}
+
+func TestIMAPSearch(t *testing.T) {
+	addr := serveIMAP(t,
+		mailText("=?UTF-8?B?VWJlciBFYXRzIOOBrumgmOWPjuabuA==?=", "Sat, 09 Mar 2024 12:00:00 +0900", "<p>receipt 1</p>"),
+		mailText("Your order with Uber Eats", "Sun, 10 Mar 2024 12:00:00 +0900", "<p>receipt 2</p>"),
+		mailText("=?UTF-8?B?VWJlciBFYXRzIOOBrumgmOWPjuabuA==?=", "Mon, 01 Apr 2024 12:00:00 +0900", "<p>receipt 3</p>"),
+	)
+	m := NewIMAP(IMAPConfig{Addr: addr, Username: "username", Password: "password", Insecure: true})
+
+	got, err := m.Search(context.Background(), &Query{Subject: "Uber Eats", After: "2024-03-01", Before: "2024-04-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	for _, msg := range got {
+		bodies = append(bodies, msg.HTML)
+	}
+	if want := "<p>receipt 1</p>,<p>receipt 2</p>"; strings.Join(bodies, ",") != want {
+		t.Errorf("HTML = %q, want %s", bodies, want)
+	}
+	if len(got) > 0 && got[0].Subject != "Uber Eats の領収書" {
+		t.Errorf("Subject = %q", got[0].Subject)
+	}
+}
+
+func TestIMAPLoginError(t *testing.T) {
+	addr := serveIMAP(t)
+	m := NewIMAP(IMAPConfig{Addr: addr, Username: "username", Password: "wrong", Insecure: true})
+	if _, err := m.Search(context.Background(), &Query{}); err == nil {
+		t.Error("want error")
+	}
+}
+
+// TestIMAPPlaintextLogin TLSもSTARTTLSも使えないサーバーには Insecure を指定しないとログインしない
+func TestIMAPPlaintextLogin(t *testing.T) {
+	addr := serveIMAP(t)
+	m := NewIMAP(IMAPConfig{Addr: addr, Username: "username", Password: "password"})
+	_, err := m.Search(context.Background(), &Query{})
+	if err == nil || !strings.Contains(err.Error(), "--imap-insecure") {
+		t.Errorf("err = %v, want an error about the plaintext login", err)
+	}
+}
+
+// TestIMAPSearchCanceled 応答しないサーバーでも ctx がキャンセルされたら終わる
+func TestIMAPSearchCanceled(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		// 接続を受け付けるだけで挨拶を返さない
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	m := NewIMAP(IMAPConfig{Addr: l.Addr().String(), Username: "username", Password: "password", Insecure: true})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Search(ctx, &Query{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Search did not return after the context was canceled")
+	}
+}
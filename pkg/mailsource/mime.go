@@ -0,0 +1,156 @@
+package mailsource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// wordDecoder 件名などのMIMEエンコードされたヘッダーをUTF-8にデコードする (ISO-2022-JPなどにも対応)
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// ParseMessage RFC 5322形式のメールを読み込む
+func ParseMessage(id string, r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{ID: id}
+	if subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		m.Subject = subject
+	} else {
+		m.Subject = msg.Header.Get("Subject")
+	}
+	if from, err := wordDecoder.DecodeHeader(msg.Header.Get("From")); err == nil {
+		m.From = from
+	} else {
+		m.From = msg.Header.Get("From")
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		m.Date = date
+	}
+
+	if err := m.readPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Header.Get("Content-Disposition"), msg.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readPart MIMEのパートを再帰的に読み込み、本文と添付ファイルを取り出す
+func (m *Message) readPart(contentType, transferEncoding, disposition string, body io.Reader) error {
+	if contentType == "" {
+		contentType = "text/plain; charset=us-ascii"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Content-Typeが壊れている場合はテキストとして扱う
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.readPart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p.Header.Get("Content-Disposition"), p); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(transferEncoding, body))
+	if err != nil {
+		return err
+	}
+
+	filename := attachmentFilename(disposition, params)
+	if filename != "" {
+		m.Attachments = append(m.Attachments, &Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Data:        data,
+		})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/html", "text/plain":
+		text, err := decodeCharset(params["charset"], data)
+		if err != nil {
+			return fmt.Errorf("本文の文字コードの変換に失敗しました: %w", err)
+		}
+		if mediaType == "text/html" && m.HTML == "" {
+			m.HTML = text
+		} else if mediaType == "text/plain" && m.Text == "" {
+			m.Text = text
+		}
+	}
+	return nil
+}
+
+// decodeTransfer Content-Transfer-Encodingをデコードする
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r) // 改行はデコーダーが無視する
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// decodeCharset 本文をUTF-8に変換する
+func decodeCharset(charset string, data []byte) (string, error) {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(data), nil
+	}
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// attachmentFilename 添付ファイルのファイル名を取得する (添付ファイルでなければ空文字)
+func attachmentFilename(disposition string, contentTypeParams map[string]string) string {
+	filename := ""
+	if disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			filename = params["filename"]
+		}
+	}
+	if filename == "" {
+		filename = contentTypeParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	return filename
+}
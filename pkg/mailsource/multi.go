@@ -0,0 +1,53 @@
+package mailsource
+
+import "context"
+
+// multi 複数のメールボックスをまとめて1つのメールボックスとして扱う
+type multi struct {
+	sources []Source
+	owners  map[MessageKey]Source // メールと、そのメールを取得したメールボックス
+}
+
+// Multi 複数のメールボックス (Gmailの複数のアカウントなど) をまとめる
+// メールボックスが1つの場合はそのまま返す
+func Multi(sources ...Source) Source {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return &multi{sources: sources, owners: map[MessageKey]Source{}}
+}
+
+// Search 全てのメールボックスを順に検索する
+func (m *multi) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	messages := []*Message{}
+	for _, src := range m.sources {
+		found, err := src.Search(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range found {
+			m.owners[msg.Key()] = src
+		}
+		messages = append(messages, found...)
+	}
+	return messages, nil
+}
+
+// Mark メールを取得したメールボックスで印を付ける
+func (m *multi) Mark(ctx context.Context, key MessageKey, processed bool) error {
+	src, ok := m.owners[key]
+	if !ok {
+		return nil
+	}
+	return Mark(ctx, src, key, processed)
+}
+
+// Checkpoint 全てのメールボックスで処理したところまでを記録する
+func (m *multi) Checkpoint(ctx context.Context) error {
+	for _, src := range m.sources {
+		if err := Checkpoint(ctx, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
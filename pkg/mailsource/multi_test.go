@@ -0,0 +1,54 @@
+package mailsource
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeSource 決まったメールを返し、付けた印を記録するメールボックス
+type fakeSource struct {
+	messages []*Message
+	marked   map[string]bool
+}
+
+func (f *fakeSource) Search(ctx context.Context, q *Query) ([]*Message, error) {
+	return f.messages, nil
+}
+
+func (f *fakeSource) Mark(ctx context.Context, key MessageKey, processed bool) error {
+	f.marked[key.ID] = processed
+	return nil
+}
+
+// TestMultiMark 同じIDのメールが複数のアカウントにあっても、取得したアカウントのメールボックスで印を付ける
+func TestMultiMark(t *testing.T) {
+	a := &fakeSource{messages: []*Message{{ID: "m1", Account: "a@example.com"}}, marked: map[string]bool{}}
+	b := &fakeSource{messages: []*Message{{ID: "m1", Account: "b@example.com"}}, marked: map[string]bool{}}
+	src := Multi(a, b)
+
+	found, err := src.Search(context.Background(), &Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("len = %d, want 2", len(found))
+	}
+
+	if err := Mark(context.Background(), src, found[0].Key(), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Mark(context.Background(), src, found[1].Key(), false); err != nil {
+		t.Fatal(err)
+	}
+	if processed, ok := a.marked["m1"]; !ok || !processed {
+		t.Errorf("a.marked = %v", a.marked)
+	}
+	if processed, ok := b.marked["m1"]; !ok || processed {
+		t.Errorf("b.marked = %v", b.marked)
+	}
+
+	// 取得していないメールには印を付けない
+	if err := Mark(context.Background(), src, MessageKey{Account: "c@example.com", ID: "m1"}, true); err != nil {
+		t.Fatal(err)
+	}
+}
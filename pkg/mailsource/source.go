@@ -0,0 +1,104 @@
+package mailsource
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Message 領収書の抽出に使うメール1通分の情報
+type Message struct {
+	ID      string    // メールを一意に識別するID (GmailのメッセージID、IMAPのUIDなど)
+	Subject string    // 件名
+	From    string    // 差出人
+	Date    time.Time // 受信日時
+	HTML    string    // HTMLの本文
+	Text    string    // テキストの本文
+	Account string    // メールを取得したアカウントのメールアドレス (GmailAPIのみ)
+
+	Attachments []*Attachment // 添付ファイル
+}
+
+// MessageKey 複数のメールボックスをまとめた場合にもメールを一意に識別する値
+// Gmailの複数のアカウントでは同じIDのメールがありうるので、アカウントと組にする
+type MessageKey struct {
+	Account string
+	ID      string
+}
+
+// Key メールを一意に識別する値
+func (m *Message) Key() MessageKey {
+	return MessageKey{Account: m.Account, ID: m.ID}
+}
+
+// Attachment メールの添付ファイル
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Query メールの検索条件
+type Query struct {
+	Subject string // 件名に含まれる文字列
+	From    string // 差出人に含まれる文字列
+	After   string // この日以降に受信したメール (format: 2024-01-01)
+	Before  string // この日より前に受信したメール (format: 2024-01-01)
+}
+
+// match 取得済みのメールが検索条件に一致するか (ローカルのファイルやGmailの差分同期で使う)
+// 日付はGmailの after: / before: と同じく、after の日を含み before の日を含まない
+func (q *Query) match(m *Message) bool {
+	if q.Subject != "" && !strings.Contains(m.Subject, q.Subject) {
+		return false
+	}
+	if q.From != "" && !strings.Contains(m.From, q.From) {
+		return false
+	}
+
+	date := m.Date.In(time.Local).Format("2006-01-02")
+	if q.After != "" && date < q.After {
+		return false
+	}
+	if q.Before != "" && date >= q.Before {
+		return false
+	}
+	return true
+}
+
+// Source 領収書のメールを取得するメールボックス
+type Source interface {
+	// Search 検索条件に一致するメールを本文付きで取得する
+	Search(ctx context.Context, q *Query) ([]*Message, error)
+}
+
+// Marker 処理したメールに印を付けられるメールボックス
+type Marker interface {
+	// Mark メールに処理済み (processed が false の場合は失敗) の印を付ける
+	Mark(ctx context.Context, key MessageKey, processed bool) error
+}
+
+// Mark メールボックスが対応していれば、処理したメールに印を付ける
+func Mark(ctx context.Context, src Source, key MessageKey, processed bool) error {
+	m, ok := src.(Marker)
+	if !ok {
+		return nil
+	}
+	return m.Mark(ctx, key, processed)
+}
+
+// Checkpointer 次回の実行のために、どこまでメールを処理したかを保存できるメールボックス
+type Checkpointer interface {
+	// Checkpoint 今回の実行で処理したところまでを記録する
+	Checkpoint(ctx context.Context) error
+}
+
+// Checkpoint メールボックスが対応していれば、どこまでメールを処理したかを保存する
+// 全てのメールを処理し終えてから呼ぶ
+func Checkpoint(ctx context.Context, src Source) error {
+	c, ok := src.(Checkpointer)
+	if !ok {
+		return nil
+	}
+	return c.Checkpoint(ctx)
+}
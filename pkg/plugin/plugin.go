@@ -0,0 +1,197 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// Prefix 外部プロバイダーの実行ファイル名の接頭辞
+const Prefix = "freeedom-provider-"
+
+// Plugin PATH上で見つかった外部プロバイダー
+type Plugin struct {
+	Name string // 実行ファイル名から接頭辞を除いた名前
+	Path string // 実行ファイルのパス
+}
+
+// Discover PATH上の外部プロバイダーを探す
+// 同じ名前のものが複数ある場合はPATHで先に見つかったものを使う
+func Discover() []*Plugin {
+	found := map[string]*Plugin{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, Prefix) || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				if !strings.EqualFold(filepath.Ext(name), ".exe") {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if info, err := entry.Info(); err != nil || info.Mode()&0o111 == 0 {
+				continue
+			}
+
+			pluginName := strings.TrimPrefix(name, Prefix)
+			if _, ok := found[pluginName]; ok || pluginName == "" {
+				continue
+			}
+			found[pluginName] = &Plugin{Name: pluginName, Path: filepath.Join(dir, entry.Name())}
+		}
+	}
+
+	plugins := make([]*Plugin, 0, len(found))
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// ProgressFunc progress イベントを受け取る関数
+type ProgressFunc func(current, total int, message string)
+
+// call 外部プロバイダーのコマンドを実行し、イベントを1つずつ handle に渡す
+func (p *Plugin) call(ctx context.Context, command string, request any, handle func(*Event) error) error {
+	cmd := exec.CommandContext(ctx, p.Path, command)
+	cmd.Stderr = os.Stderr
+
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(b)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("外部プロバイダー %s の起動に失敗しました: %w", p.Name, err)
+	}
+
+	var handleErr error
+	scanner := bufio.NewScanner(stdout)
+	// file イベントはPDFをbase64で含むので大きめのバッファを使う
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || handleErr != nil {
+			continue
+		}
+
+		event := &Event{}
+		if err := json.Unmarshal(line, event); err != nil {
+			handleErr = fmt.Errorf("外部プロバイダー %s の出力が不正です: %w", p.Name, err)
+			continue
+		}
+		if event.Type == EventError {
+			handleErr = fmt.Errorf("外部プロバイダー %s でエラーが発生しました: %s", p.Name, event.Message)
+			continue
+		}
+		handleErr = handle(event)
+	}
+	if err := scanner.Err(); err != nil && handleErr == nil {
+		handleErr = err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return errors.Join(handleErr, fmt.Errorf("外部プロバイダー %s の %s が失敗しました: %w", p.Name, command, err))
+	}
+	return handleErr
+}
+
+// Describe 外部プロバイダーの名前と説明を取得する
+func (p *Plugin) Describe(ctx context.Context) (*Event, error) {
+	var description *Event
+	err := p.call(ctx, "describe", nil, func(e *Event) error {
+		if e.Type == EventDescribe {
+			description = e
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if description == nil {
+		return nil, fmt.Errorf("外部プロバイダー %s が describe イベントを返しませんでした", p.Name)
+	}
+	if description.ProtocolVersion < 1 || description.ProtocolVersion > ProtocolVersion {
+		return nil, fmt.Errorf("外部プロバイダー %s のプロトコルバージョン %d には対応していません", p.Name, description.ProtocolVersion)
+	}
+	return description, nil
+}
+
+// List 期間内の領収書の一覧を取得する
+func (p *Plugin) List(ctx context.Context, after, before string, progress ProgressFunc) ([]*receipt.Receipt, error) {
+	receipts := []*receipt.Receipt{}
+	err := p.call(ctx, "list", &ListRequest{After: after, Before: before}, func(e *Event) error {
+		switch e.Type {
+		case EventProgress:
+			progress(e.Current, e.Total, e.Message)
+		case EventReceipt:
+			if e.Receipt == nil || e.Receipt.ID == "" {
+				return fmt.Errorf("外部プロバイダー %s が id のない領収書を返しました", p.Name)
+			}
+			if e.Receipt.Provider == "" {
+				e.Receipt.Provider = p.Name
+			}
+			receipts = append(receipts, e.Receipt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// File fetch で受け取ったファイル
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Fetch 領収書のファイルを取得する (1つ目が領収書、2つ目以降は添付資料)
+func (p *Plugin) Fetch(ctx context.Context, r *receipt.Receipt, progress ProgressFunc) ([]*File, error) {
+	files := []*File{}
+	err := p.call(ctx, "fetch", &FetchRequest{Receipt: r}, func(e *Event) error {
+		switch e.Type {
+		case EventProgress:
+			progress(e.Current, e.Total, e.Message)
+		case EventFile:
+			if e.FileName == "" {
+				return fmt.Errorf("外部プロバイダー %s が file_name のないファイルを返しました", p.Name)
+			}
+			files = append(files, &File{Name: filepath.Base(e.FileName), Data: e.Data})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("外部プロバイダー %s が領収書 %s のファイルを返しませんでした", p.Name, r.ID)
+	}
+	return files, nil
+}
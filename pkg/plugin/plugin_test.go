@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// fakeProvider 外部プロバイダーとして動くシェルスクリプト
+// FAKE_* の環境変数で describe のバージョンやエラーの返し方を変える
+const fakeProvider = `#!/bin/sh
+case "$1" in
+describe)
+	echo '{"type": "describe", "name": "fake", "description": "test", "protocol_version": '"${FAKE_PROTOCOL_VERSION:-1}"'}'
+	;;
+list)
+	cat > "$FAKE_DIR/list-request.json"
+	if [ -n "$FAKE_LIST_ERROR" ]; then
+		echo '{"type": "error", "message": "'"$FAKE_LIST_ERROR"'"}'
+		exit 0
+	fi
+	echo '{"type": "progress", "current": 1, "total": 2, "message": "page 1"}'
+	echo ''
+	echo '{"type": "receipt", "receipt": {"id": "r1", "date": "2024-01-05", "total": 1100}}'
+	echo '{"type": "receipt", "receipt": {"provider": "other", "id": "a/b", "date": "2024-01-06", "total": 2200}}'
+	;;
+fetch)
+	cat >> "$FAKE_DIR/fetch-requests.json"
+	echo >> "$FAKE_DIR/fetch-requests.json"
+	if [ -n "$FAKE_FETCH_EXIT" ]; then
+		echo "fetch failed" >&2
+		exit "$FAKE_FETCH_EXIT"
+	fi
+	echo '{"type": "file", "file_name": "../../receipt.pdf", "data": "JVBERi0xLjQK"}'
+	echo '{"type": "file", "file_name": "detail.csv", "data": "YSxiCg=="}'
+	;;
+*)
+	exit 2
+	;;
+esac
+`
+
+// newFakePlugin 一時ディレクトリに置いたシェルスクリプトの外部プロバイダー
+func newFakePlugin(t *testing.T) (*Plugin, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script provider is not supported on windows")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, Prefix+"fake")
+	if err := os.WriteFile(path, []byte(fakeProvider), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_DIR", dir)
+	return &Plugin{Name: "fake", Path: path}, dir
+}
+
+func TestDescribe(t *testing.T) {
+	p, _ := newFakePlugin(t)
+	got, err := p.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "fake" || got.Description != "test" || got.ProtocolVersion != 1 {
+		t.Errorf("Describe() = %+v", got)
+	}
+
+	for _, version := range []string{"0", "2"} {
+		t.Setenv("FAKE_PROTOCOL_VERSION", version)
+		if _, err := p.Describe(context.Background()); err == nil {
+			t.Errorf("protocol_version %s: want error", version)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	p, dir := newFakePlugin(t)
+	var progress []string
+	receipts, err := p.List(context.Background(), "2024-01-01", "2024-01-31", func(current, total int, message string) {
+		progress = append(progress, message)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(receipts) != 2 || receipts[0].ID != "r1" || receipts[0].Total != 1100 {
+		t.Fatalf("List() = %+v", receipts)
+	}
+	// provider を省略した領収書はプロバイダー名にする
+	if receipts[0].Provider != "fake" || receipts[1].Provider != "other" {
+		t.Errorf("providers = %q, %q", receipts[0].Provider, receipts[1].Provider)
+	}
+	if strings.Join(progress, ",") != "page 1" {
+		t.Errorf("progress = %q", progress)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "list-request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &ListRequest{}
+	if err := json.Unmarshal(b, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.After != "2024-01-01" || req.Before != "2024-01-31" {
+		t.Errorf("list request = %+v", req)
+	}
+}
+
+func TestListErrorEvent(t *testing.T) {
+	p, _ := newFakePlugin(t)
+	t.Setenv("FAKE_LIST_ERROR", "login required")
+	_, err := p.List(context.Background(), "2024-01-01", "2024-01-31", printProgress)
+	if err == nil || !strings.Contains(err.Error(), "login required") {
+		t.Errorf("err = %v, want the error event message", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	p, _ := newFakePlugin(t)
+	files, err := p.Fetch(context.Background(), &receipt.Receipt{ID: "r1"}, printProgress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || string(files[0].Data) != "%PDF-1.4\n" || string(files[1].Data) != "a,b\n" {
+		t.Errorf("Fetch() = %+v", files)
+	}
+}
+
+func TestFetchExitCode(t *testing.T) {
+	p, _ := newFakePlugin(t)
+	t.Setenv("FAKE_FETCH_EXIT", "3")
+	if _, err := p.Fetch(context.Background(), &receipt.Receipt{ID: "r1"}, printProgress); err == nil {
+		t.Error("want error for non-zero exit code")
+	}
+}
+
+func TestRun(t *testing.T) {
+	p, dir := newFakePlugin(t)
+	outputDir := t.TempDir()
+	if err := Run(context.Background(), p, "2024-01-01", "2024-01-31", outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// ファイル名はプロバイダー名と領収書のIDから決め、プロバイダーが返したファイル名のパスは使わない
+	for _, name := range []string{"fake_r1.pdf", "fake_r1_2.csv", "fake_r1.json", "fake_a_b.pdf", "fake_a_b_2.csv", "fake_a_b.json"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outputDir), "receipt.pdf")); err == nil {
+		t.Error("wrote the file outside the output directory")
+	}
+
+	b, err := os.ReadFile(filepath.Join(outputDir, "fake_r1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &receipt.Receipt{}
+	if err := json.Unmarshal(b, r); err != nil {
+		t.Fatal(err)
+	}
+	if r.PDFFile != "fake_r1.pdf" || strings.Join(r.Attachments, ",") != "fake_r1_2.csv" {
+		t.Errorf("sidecar = %+v", r)
+	}
+
+	fetched, err := os.ReadFile(filepath.Join(dir, "fetch-requests.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(fetched), `"receipt"`); n != 2 {
+		t.Errorf("fetch requests = %d, want 2", n)
+	}
+}
+
+// TestRunUnsupportedProtocol 対応していないプロトコルのプロバイダーには list を送らないことを確認する
+func TestRunUnsupportedProtocol(t *testing.T) {
+	p, dir := newFakePlugin(t)
+	t.Setenv("FAKE_PROTOCOL_VERSION", "2")
+	if err := Run(context.Background(), p, "2024-01-01", "2024-01-31", t.TempDir()); err == nil {
+		t.Fatal("want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "list-request.json")); err == nil {
+		t.Error("list was called")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	for _, tt := range []struct {
+		id    string
+		index int
+		name  string
+		want  string
+	}{
+		{"123", 0, "invoice.pdf", "example_123.pdf"},
+		{"123", 0, "", "example_123.pdf"},
+		{"123", 1, "detail.csv", "example_123_2.csv"},
+		{"../x", 0, "../../x.pdf", "example_.._x.pdf"},
+		{`a\b:c`, 2, "y.PDF", "example_a_b_c_3.PDF"},
+	} {
+		if got := fileName("example", tt.id, tt.index, tt.name); got != tt.want {
+			t.Errorf("fileName(%q, %d, %q) = %q, want %q", tt.id, tt.index, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package plugin PATH上の freeedom-provider-* 実行ファイルを外部プロバイダーとして扱う
+//
+// freeedom は外部プロバイダーを `freeedom-provider-{名前} {コマンド}` として起動し、
+// リクエストを1つのJSONとして標準入力に書き込み、標準出力から1行1つのJSONのイベントを読み込む。
+// 標準エラー出力はそのまま利用者の端末に表示する。
+//
+// コマンド:
+//
+//	describe  リクエストなし。describe イベントを1つ返す
+//	list      {"after": "2024-01-01", "before": "2024-01-31"} を受け取り、期間内の領収書ごとに receipt イベントを返す
+//	fetch     {"receipt": {...}} を受け取り、領収書のファイルを file イベントで返す (1つ目が領収書、2つ目以降は添付資料)
+//
+// イベント:
+//
+//	{"type": "describe", "name": "example", "description": "...", "protocol_version": 1}
+//	{"type": "progress", "current": 1, "total": 10, "message": "..."}
+//	{"type": "receipt", "receipt": {"id": "...", "date": "2024-01-05", "total": 1100, ...}}
+//	{"type": "file", "file_name": "123.pdf", "data": "(base64)"}
+//	{"type": "error", "message": "..."}
+//
+// receipt の形式は領収書のメタデータJSONと同じ。provider を省略した場合はプロバイダー名を使う。
+// freeedom は list の前に describe を実行し、protocol_version に対応していないプロバイダーは使わない。
+// 保存するファイル名はプロバイダー名と領収書のIDから決め、file_name は拡張子だけを使う (例: example_123.pdf)。
+// 終了コードが0以外、または error イベントを返した場合は失敗として扱う。
+package plugin
+
+import (
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// ProtocolVersion freeedomが対応しているプロトコルのバージョン
+const ProtocolVersion = 1
+
+// イベントの種類
+const (
+	EventDescribe = "describe"
+	EventProgress = "progress"
+	EventReceipt  = "receipt"
+	EventFile     = "file"
+	EventError    = "error"
+)
+
+// Event 外部プロバイダーが標準出力に書き込む1行分のイベント
+type Event struct {
+	Type string `json:"type"`
+
+	// describe
+	Name            string `json:"name,omitempty"`
+	Description     string `json:"description,omitempty"`
+	ProtocolVersion int    `json:"protocol_version,omitempty"`
+
+	// progress
+	Current int    `json:"current,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Message string `json:"message,omitempty"` // progress, error
+
+	// receipt
+	Receipt *receipt.Receipt `json:"receipt,omitempty"`
+
+	// file
+	FileName string `json:"file_name,omitempty"`
+	Data     []byte `json:"data,omitempty"` // base64
+}
+
+// ListRequest list コマンドのリクエスト
+type ListRequest struct {
+	After  string `json:"after"`  // format: 2024-01-01
+	Before string `json:"before"` // format: 2024-01-31
+}
+
+// FetchRequest fetch コマンドのリクエスト
+type FetchRequest struct {
+	Receipt *receipt.Receipt `json:"receipt"`
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Run 外部プロバイダーから期間内の領収書を取得し、PDFとメタデータ・レポートを保存する
+// after, before: format: 2024-01-01
+func Run(ctx context.Context, p *Plugin, after, before, outputDir string) error {
+	// 対応していないプロトコルのプロバイダーには list を送らない
+	if _, err := p.Describe(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("外部プロバイダー %s から領収書の一覧を取得します。\n", p.Name)
+	receipts, err := p.List(ctx, after, before, printProgress)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("領収書の一覧を取得しました 件数:", len(receipts))
+
+	// 領収書をダウンロード
+	fmt.Println("領収書をダウンロードします")
+	report := receipt.NewReport(p.Name, after, before)
+	bar := progressbar.Default(int64(len(receipts)))
+	for _, r := range receipts {
+		files, err := p.Fetch(ctx, r, func(int, int, string) {})
+		if err != nil {
+			return err
+		}
+
+		var pdfPath string
+		for i, f := range files {
+			name := fileName(p.Name, r.ID, i, f.Name)
+			path, err := receipt.WritePDF(outputDir, name, f.Data)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				pdfPath = path
+				r.PDFFile = name
+			} else {
+				r.Attachments = append(r.Attachments, name)
+			}
+		}
+
+		if _, err := receipt.WriteSidecar(pdfPath, r); err != nil {
+			return err
+		}
+		report.Add(r)
+		bar.Add(1)
+	}
+
+	reportPath, err := report.Write(outputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Println("レポートを保存しました:", reportPath)
+
+	return nil
+}
+
+// fileName 保存するファイル名 (例: example_123.pdf、添付資料は example_123_2.csv)
+// 別のプロバイダーや領収書のファイルを上書きしないように、プロバイダーが返したファイル名は拡張子だけを使う
+func fileName(provider, id string, index int, name string) string {
+	ext := filepath.Ext(name)
+	if ext == "" && index == 0 {
+		ext = ".pdf"
+	}
+	base := provider + "_" + strings.Map(safeFileNameRune, id)
+	if index > 0 {
+		base = fmt.Sprintf("%s_%d", base, index+1)
+	}
+	return base + strings.Map(safeFileNameRune, ext)
+}
+
+// safeFileNameRune ファイル名に使えない文字 (パスの区切りなど) を "_" にする
+func safeFileNameRune(r rune) rune {
+	if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+		return '_'
+	}
+	return r
+}
+
+// printProgress 外部プロバイダーの進捗を表示する
+func printProgress(current, total int, message string) {
+	if total > 0 {
+		fmt.Printf("[%d/%d] %s\n", current, total, message)
+	} else {
+		fmt.Println(message)
+	}
+}
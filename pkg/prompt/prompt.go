@@ -0,0 +1,74 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// ReadLine 1行入力させる
+func ReadLine(label string) string {
+	fmt.Printf("%s: ", label)
+	line := ""
+	fmt.Scanln(&line)
+	return line
+}
+
+// IsTerminal 標準入力が端末か (入力させられるか)
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Confirm はい/いいえを入力させる (何も入力しなければはい)
+func Confirm(label string) bool {
+	answer := strings.ToLower(ReadLine(label + " (Y/n)"))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// ConfirmNo はい/いいえを入力させる (何も入力しなければいいえ)
+// 削除など取り消せない操作の前に使い、Enterを押しただけでは実行しない
+func ConfirmNo(label string) bool {
+	answer := strings.ToLower(ReadLine(label + " (y/N)"))
+	return answer == "y" || answer == "yes"
+}
+
+// ReadPassword パスワード入力モードで入力させる
+func ReadPassword() ([]byte, error) {
+	// Ctrl+Cのシグナルをキャプチャする
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt)
+	defer signal.Stop(signalChan)
+
+	// 現在のターミナルの状態をコピーしておく
+	currentState, err := term.GetState(int(syscall.Stdin))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-signalChan
+		// Ctrl+Cを受信後、ターミナルの状態を先ほどのコピーを用いて元に戻す
+		term.Restore(int(syscall.Stdin), currentState)
+		os.Exit(1)
+	}()
+
+	return term.ReadPassword(syscall.Stdin)
+}
+
+// ReadCredentials メールアドレスとパスワードを入力させる
+func ReadCredentials() (string, string, error) {
+	fmt.Println("ログイン情報を入力してください。")
+	email := ReadLine("メールアドレス📩")
+	fmt.Printf("パスワード🔑: ")
+	password, err := ReadPassword()
+	if err != nil {
+		return "", "", err
+	}
+	fmt.Println()
+
+	return email, string(password), nil
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/credential"
+)
+
+// Options 全てのプロバイダーに共通の実行オプション
+type Options struct {
+	After     string // 検索範囲の開始日 (format: 2024-01-01)
+	Before    string // 検索範囲の終了日 (format: 2024-01-31)
+	OutputDir string // 出力先ディレクトリ
+
+	GmailCredentials string // GmailAPIのクライアントJSONのパス (Gmailを使うプロバイダーのみ)
+
+	SiteCredentials credential.Options // サイトのログイン情報の取得方法 (ブラウザでログインするプロバイダーのみ)
+	Profile         string             // 領収書の宛名と印刷設定のプロファイル名 (サイト定義で取得するプロバイダーのみ)
+}
+
+// YearMonth 日付 (2024-01-01) を年月単位のプロバイダー向けの形式 (202401) にする
+func YearMonth(date string) string {
+	ym := strings.ReplaceAll(date, "-", "")
+	if len(ym) > 6 {
+		ym = ym[:6]
+	}
+	return ym
+}
+
+// Provider 領収書の取得元
+type Provider struct {
+	Name  string // 名前 (サブコマンド名と同じ)
+	Short string // 説明
+	Run   func(ctx context.Context, opts *Options) error
+}
+
+var providers = map[string]*Provider{}
+
+// Register プロバイダーを登録する
+func Register(p *Provider) {
+	providers[p.Name] = p
+}
+
+// Find 名前でプロバイダーを探す
+func Find(name string) (*Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("プロバイダーが見つかりません: %s (利用可能: %s)", name, strings.Join(Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names 登録されているプロバイダーの名前の一覧
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,130 @@
+package receipt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// currencySymbols 金額の記号と通貨コード
+var currencySymbols = map[string]string{
+	"¥":   "JPY",
+	"￥":   "JPY",
+	"JP¥": "JPY",
+	"$":   "USD",
+	"US$": "USD",
+	"A$":  "AUD",
+	"CA$": "CAD",
+	"HK$": "HKD",
+	"NT$": "TWD",
+	"S$":  "SGD",
+	"€":   "EUR",
+	"£":   "GBP",
+}
+
+var (
+	currencyRe     = regexp.MustCompile(`(?:[A-Z]{1,3}\s?)?[￥¥$€£]`)
+	currencyCodeRe = regexp.MustCompile(`\b(?:JPY|USD|AUD|CAD|HKD|TWD|SGD|EUR|GBP)\b`) // 記号の代わりに通貨コードを書いた金額 (例: 12.99 USD)
+	numberRe       = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+)
+
+// currencyDigits 通貨ごとの小数点以下の桁数 (表にない外貨は2桁とする)
+// 金額は通貨の最小単位 (日本円は円、米ドルはセント) の整数で扱う
+var currencyDigits = map[string]int{
+	"":    0,
+	"JPY": 0,
+}
+
+// Digits 通貨の小数点以下の桁数
+func Digits(currency string) int {
+	if d, ok := currencyDigits[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// ParseMoney ¥1,234 や $12.34 のような金額を、通貨の最小単位の整数と通貨コードに変換する (例: $12.34 → 1234, USD)
+// 通貨の桁数より細かい端数は四捨五入する。日本円の場合は通貨コードを空で返す
+// 数値より前にマイナス記号 (-, −, ▲) がある場合は負の金額にする (例: -¥100, ¥-100, ▲100円)
+func ParseMoney(s string) (int, string) {
+	currency := ""
+	if symbol := currencyRe.FindString(s); symbol != "" {
+		symbol = strings.ReplaceAll(symbol, " ", "")
+		currency = currencySymbols[symbol]
+		if currency == "" {
+			currency = symbol
+		}
+	} else {
+		currency = currencyCodeRe.FindString(s)
+	}
+	if currency == "JPY" {
+		currency = ""
+	}
+	return ParseMoneyIn(s, currency), currency
+}
+
+// ParseMoneyIn 通貨を指定して、金額を通貨の最小単位の整数に変換する (金額の通貨記号は見ない)
+// 通貨記号のない金額 (例: 12.34) をサイトの通貨で読む場合に使う
+func ParseMoneyIn(s, currency string) int {
+	loc := numberRe.FindStringIndex(s)
+	if loc == nil {
+		return 0
+	}
+	whole, frac, _ := strings.Cut(strings.ReplaceAll(s[loc[0]:loc[1]], ",", ""), ".")
+
+	// 浮動小数点数を通さずに桁をずらして、端数を四捨五入する
+	digits := Digits(currency)
+	frac += strings.Repeat("0", digits)
+	amount, err := strconv.Atoi(whole + frac[:digits])
+	if err != nil {
+		return 0
+	}
+	if len(frac) > digits && frac[digits] >= '5' {
+		amount++
+	}
+	if strings.ContainsAny(s[:loc[0]], "-−▲") {
+		amount = -amount
+	}
+	return amount
+}
+
+// FormatMoney 通貨の最小単位の整数を表示用の文字列にする (例: 1234, USD → $12.34、1234, 空 → ¥1,234)
+// ParseMoney で元の金額と通貨に戻せる
+func FormatMoney(amount int, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := Digits(currency)
+	unit := 1
+	for i := 0; i < digits; i++ {
+		unit *= 10
+	}
+	number := commas(amount / unit)
+	if digits > 0 {
+		number += fmt.Sprintf(".%0*d", digits, amount%unit)
+	}
+
+	switch currency {
+	case "", "JPY":
+		return sign + "¥" + number
+	case "USD":
+		return sign + "$" + number
+	case "EUR":
+		return sign + "€" + number
+	case "GBP":
+		return sign + "£" + number
+	}
+	return sign + number + " " + currency
+}
+
+// commas 3桁ごとにカンマで区切る
+func commas(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
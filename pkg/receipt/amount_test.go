@@ -0,0 +1,79 @@
+package receipt
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		amount   int
+		currency string
+	}{
+		{"¥1,234", 1234, ""},
+		{"￥ 1,234", 1234, ""},
+		{"JP¥500", 500, ""},
+		{"1,100円", 1100, ""},
+		{"$12.34", 1234, "USD"},
+		{"$12.50", 1250, "USD"},
+		{"US$1,000.49", 100049, "USD"},
+		{"12.99 USD", 1299, "USD"},
+		{"€9.99", 999, "EUR"},
+		{"$0.125", 13, "USD"},
+		{"$5", 500, "USD"},
+		{"¥1,234.5", 1235, ""},
+		{"-$1.50", -150, "USD"},
+		{"-¥100", -100, ""},
+		{"¥-100", -100, ""},
+		{"−¥1,000", -1000, ""},
+		{"▲100円", -100, ""},
+		{"Total, ¥1,234", 1234, ""},
+		{"", 0, ""},
+		{"無料", 0, ""},
+	} {
+		amount, currency := ParseMoney(tt.s)
+		if amount != tt.amount || currency != tt.currency {
+			t.Errorf("ParseMoney(%q) = %d, %q, want %d, %q", tt.s, amount, currency, tt.amount, tt.currency)
+		}
+	}
+}
+
+// TestFormatMoney 外貨の金額が端数を失わずに元の文字列に戻ることを確認する
+func TestFormatMoney(t *testing.T) {
+	for _, s := range []string{"$12.34", "$0.05", "-$1.50", "$1,000,000.00", "¥1,234", "¥0", "-¥100", "€9.99", "£1.00", "12.34 AUD"} {
+		amount, currency := ParseMoney(s)
+		if got := FormatMoney(amount, currency); got != s {
+			t.Errorf("FormatMoney(ParseMoney(%q)) = %q (%d %s)", s, got, amount, currency)
+		}
+	}
+}
+
+func TestParseMoneyIn(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		currency string
+		want     int
+	}{
+		{"12.34", "USD", 1234},
+		{"12.34", "", 12},
+		{"1,000", "USD", 100000},
+		{"100コイン", "", 100},
+	} {
+		if got := ParseMoneyIn(tt.s, tt.currency); got != tt.want {
+			t.Errorf("ParseMoneyIn(%q, %q) = %d, want %d", tt.s, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	for s, want := range map[string]int{
+		"1,100円":   1100,
+		"¥1,100":   1100,
+		"-1,100円":  -1100,
+		"1,100.6円": 1101,
+		"100コイン":   100,
+		"":         0,
+	} {
+		if got := ParseAmount(s); got != want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
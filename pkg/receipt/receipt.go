@@ -0,0 +1,162 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Receipt 領収書1件分のメタデータ
+type Receipt struct {
+	Provider      string   `json:"provider"`                 // 取得元 (bookwalker, ubereats など)
+	ID            string   `json:"id"`                       // 取得元での領収書ID
+	Type          string   `json:"type,omitempty"`           // 取得元での領収書の種類 (ubereats: eats, rides)
+	Vendor        string   `json:"vendor,omitempty"`         // 領収書の発行者
+	Region        string   `json:"region,omitempty"`         // 取得元のストアの地域 (bookwalker: jp, global)
+	URL           string   `json:"url,omitempty"`            // 領収書ページのURL
+	Date          string   `json:"date"`                     // 購入日 (format: 2024-01-01)
+	Total         int      `json:"total"`                    // 支払合計金額 (通貨の最小単位。日本円は円、米ドルはセント)
+	Currency      string   `json:"currency,omitempty"`       // 通貨コード (空の場合は日本円)
+	PaymentMethod string   `json:"payment_method,omitempty"` // 支払方法
+	PaymentType   string   `json:"payment_type,omitempty"`   // 支払の種類 (purchase, coin, point, subscription。空の場合は purchase)
+	CashAmount    int      `json:"cash_amount,omitempty"`    // コイン・ポイントを除いた現金 (クレジットカードなど) の支払額
+	CoinUsage     int      `json:"coin_usage,omitempty"`     // コイン利用額
+	PointUsage    int      `json:"point_usage,omitempty"`    // ポイント利用額
+	Items         []string `json:"items,omitempty"`          // 購入した商品名
+	PDFFile       string   `json:"pdf_file,omitempty"`       // 保存したPDFのファイル名
+	Attachments   []string `json:"attachments,omitempty"`    // 領収書と一緒に保存した請求書などのファイル名
+	Account       string   `json:"account,omitempty"`        // 領収書のメールを取得したアカウントのメールアドレス
+	Addressee     string   `json:"addressee,omitempty"`      // 領収書に入力した宛名
+
+	RegistrationNumber string `json:"registration_number,omitempty"` // 適格請求書発行事業者の登録番号 (T + 13桁)
+
+	Ride *Ride `json:"ride,omitempty"` // 乗車の詳細 (配車サービスの領収書のみ)
+}
+
+// Ride 配車サービスの乗車の詳細
+type Ride struct {
+	Pickup      string `json:"pickup,omitempty"`       // 乗車地
+	PickupTime  string `json:"pickup_time,omitempty"`  // 乗車時刻
+	Dropoff     string `json:"dropoff,omitempty"`      // 降車地
+	DropoffTime string `json:"dropoff_time,omitempty"` // 降車時刻
+	Distance    string `json:"distance,omitempty"`     // 距離 (例: 5.2 km)
+	Fares       []Fare `json:"fares,omitempty"`        // 料金の内訳
+}
+
+// Fare 料金の内訳1行分
+type Fare struct {
+	Label  string `json:"label"`
+	Amount int    `json:"amount"` // 領収書の通貨の最小単位の金額 (割引などはマイナス)
+}
+
+// WritePDF 出力先ディレクトリにPDFを保存する (ディレクトリがなければ作成する)
+func WritePDF(outputDir, fileName string, pdf []byte) (string, error) {
+	pdfPath := filepath.Join(outputDir, fileName)
+
+	if err := os.MkdirAll(filepath.Dir(pdfPath), 0o755); err != nil {
+		return "", fmt.Errorf("出力先ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	if err := os.WriteFile(pdfPath, pdf, 0o644); err != nil {
+		return "", fmt.Errorf("PDFの保存に失敗しました : %w", err)
+	}
+
+	return pdfPath, nil
+}
+
+// SidecarPath PDFのパスからメタデータJSONのパスを作る (例: 123.pdf -> 123.json)
+func SidecarPath(pdfPath string) string {
+	return strings.TrimSuffix(pdfPath, filepath.Ext(pdfPath)) + ".json"
+}
+
+// WriteSidecar 領収書のメタデータをPDFと同じディレクトリにJSONで保存する
+func WriteSidecar(pdfPath string, r *Receipt) (string, error) {
+	sidecarPath := SidecarPath(pdfPath)
+	if err := writeJSON(sidecarPath, r); err != nil {
+		return "", fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+	return sidecarPath, nil
+}
+
+// LoadDir ディレクトリに保存されているメタデータJSONを全て読み込む
+func LoadDir(dir string) ([]*Receipt, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := []*Receipt{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &Receipt{}
+		if err := json.Unmarshal(b, r); err != nil {
+			return nil, fmt.Errorf("メタデータの読み込みに失敗しました %s: %w", path, err)
+		}
+
+		// 領収書のメタデータ以外のJSONは無視する
+		if r.Provider == "" || r.ID == "" {
+			continue
+		}
+		receipts = append(receipts, r)
+	}
+
+	return receipts, nil
+}
+
+// Filter 購入日が期間内の領収書だけを返す
+// after, before: format: 2024-01-01 (空の場合は制限しない)
+func Filter(receipts []*Receipt, after, before string) []*Receipt {
+	filtered := []*Receipt{}
+	for _, r := range receipts {
+		if after != "" && r.Date < after {
+			continue
+		}
+		if before != "" && r.Date > before {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+var dateRe = regexp.MustCompile(`(\d{4})\s*[/年.\-]\s*(\d{1,2})\s*[/月.\-]\s*(\d{1,2})`)
+
+// NormalizeDate 2024/1/5, 2024年1月5日 のような日付を 2024-01-05 に変換する
+// 日付が含まれていない場合は空文字を返す
+func NormalizeDate(s string) string {
+	m := dateRe.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	return fmt.Sprintf("%s-%02d-%02d", m[1], month, day)
+}
+
+// ParseAmount 1,100円 のような日本円の金額の文字列を数値に変換する
+// 最初の数値だけを読み、小数点以下は四捨五入し、マイナス記号があれば負の金額にする
+// 数字が含まれていない場合は0を返す。外貨の金額は通貨も分かる ParseMoney を使う
+func ParseAmount(s string) int {
+	return ParseMoneyIn(s, "")
+}
+
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
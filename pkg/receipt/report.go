@@ -0,0 +1,103 @@
+package receipt
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Report 1回の実行で取得した領収書の一覧
+type Report struct {
+	Provider  string     `json:"provider"`
+	CreatedAt time.Time  `json:"created_at"`
+	After     string     `json:"after"`
+	Before    string     `json:"before,omitempty"`
+	Count     int        `json:"count"`
+	Total     int        `json:"total"` // 日本円の領収書の合計金額
+	Receipts  []*Receipt `json:"receipts"`
+
+	// 外貨建ての領収書の通貨ごとの合計金額 (通貨の最小単位)
+	ForeignTotals map[string]int `json:"foreign_totals,omitempty"`
+
+	// 登録番号が見つからなかった (適格請求書の要件を満たしていない可能性がある) 領収書のID
+	NoRegistrationNumber []string `json:"no_registration_number,omitempty"`
+
+	// 領収書のページがなく購入情報だけを保存した (登録番号を確認できない) 行のID
+	MetadataOnly []string `json:"metadata_only,omitempty"`
+
+	// 取得しなかった購入履歴の行と理由
+	Skipped []*Skipped `json:"skipped,omitempty"`
+}
+
+// Skipped 取得しなかった購入履歴の行
+type Skipped struct {
+	ID          string   `json:"id"`
+	Date        string   `json:"date"`
+	Total       int      `json:"total"`
+	Currency    string   `json:"currency,omitempty"`
+	PaymentType string   `json:"payment_type,omitempty"`
+	Items       []string `json:"items,omitempty"`
+	Reason      string   `json:"reason"`
+}
+
+// NewReport レポートを作成する
+func NewReport(provider, after, before string) *Report {
+	return &Report{
+		Provider:  provider,
+		CreatedAt: time.Now(),
+		After:     after,
+		Before:    before,
+		Receipts:  []*Receipt{},
+	}
+}
+
+// Add 領収書をレポートに追加する
+func (r *Report) Add(receipt *Receipt) {
+	r.add(receipt)
+	if receipt.RegistrationNumber == "" {
+		r.NoRegistrationNumber = append(r.NoRegistrationNumber, receipt.ID)
+	}
+}
+
+// AddMetadataOnly 領収書のページがなく購入情報だけを保存した行をレポートに追加する
+// 登録番号を探す元がないので no_registration_number ではなく metadata_only に記録する
+func (r *Report) AddMetadataOnly(receipt *Receipt) {
+	r.add(receipt)
+	r.MetadataOnly = append(r.MetadataOnly, receipt.ID)
+}
+
+func (r *Report) add(receipt *Receipt) {
+	r.Receipts = append(r.Receipts, receipt)
+	r.Count = len(r.Receipts)
+	if receipt.Currency == "" {
+		r.Total += receipt.Total
+	} else {
+		if r.ForeignTotals == nil {
+			r.ForeignTotals = map[string]int{}
+		}
+		r.ForeignTotals[receipt.Currency] += receipt.Total
+	}
+}
+
+// Skip 取得しなかった領収書と理由をレポートに追加する
+func (r *Report) Skip(receipt *Receipt, reason string) {
+	r.Skipped = append(r.Skipped, &Skipped{
+		ID:          receipt.ID,
+		Date:        receipt.Date,
+		Total:       receipt.Total,
+		Currency:    receipt.Currency,
+		PaymentType: receipt.PaymentType,
+		Items:       receipt.Items,
+		Reason:      reason,
+	})
+}
+
+// Write 出力先ディレクトリの reports/ 以下にレポートを保存する
+func (r *Report) Write(outputDir string) (string, error) {
+	fileName := fmt.Sprintf("%s_%s.json", r.Provider, r.CreatedAt.Format("20060102-150405"))
+	reportPath := filepath.Join(outputDir, "reports", fileName)
+	if err := writeJSON(reportPath, r); err != nil {
+		return "", fmt.Errorf("レポートの保存に失敗しました: %w", err)
+	}
+	return reportPath, nil
+}
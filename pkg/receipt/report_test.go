@@ -0,0 +1,30 @@
+package receipt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReportAdd(t *testing.T) {
+	report := NewReport("test", "2024-01-01", "")
+	report.Add(&Receipt{ID: "with-number", Total: 1000, RegistrationNumber: "T9234567890123"})
+	report.Add(&Receipt{ID: "without-number", Total: 500})
+	report.Add(&Receipt{ID: "usd", Total: 1234, Currency: "USD"})
+	report.AddMetadataOnly(&Receipt{ID: "coin", Total: 300})
+
+	if report.Count != 4 {
+		t.Errorf("Count = %d, want 4", report.Count)
+	}
+	if report.Total != 1800 {
+		t.Errorf("Total = %d, want 1800", report.Total)
+	}
+	if want := map[string]int{"USD": 1234}; !reflect.DeepEqual(report.ForeignTotals, want) {
+		t.Errorf("ForeignTotals = %v, want %v", report.ForeignTotals, want)
+	}
+	if want := []string{"without-number", "usd"}; !reflect.DeepEqual(report.NoRegistrationNumber, want) {
+		t.Errorf("NoRegistrationNumber = %v, want %v", report.NoRegistrationNumber, want)
+	}
+	if want := []string{"coin"}; !reflect.DeepEqual(report.MetadataOnly, want) {
+		t.Errorf("MetadataOnly = %v, want %v", report.MetadataOnly, want)
+	}
+}
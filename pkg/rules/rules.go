@@ -0,0 +1,301 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule 領収書の条件と、当てはまった場合に割り当てる勘定科目などの組み合わせ
+type Rule struct {
+	Name string `yaml:"name,omitempty"` // ルールの名前 (rules test の表示用)
+
+	// 条件 (空の項目は条件にしない)
+	Provider  string   `yaml:"provider,omitempty"`   // 取得元 (完全一致)
+	Type      string   `yaml:"type,omitempty"`       // 領収書の種類 (完全一致、例: ubereats の eats / rides)
+	Vendor    string   `yaml:"vendor,omitempty"`     // 発行者 (部分一致)
+	Item      string   `yaml:"item,omitempty"`       // 商品名 (正規表現、いずれかの商品に一致すれば良い)
+	MinAmount *int     `yaml:"min_amount,omitempty"` // 支払合計金額の下限 (円、この金額を含む)
+	MaxAmount *int     `yaml:"max_amount,omitempty"` // 支払合計金額の上限 (円、この金額を含む)
+	Weekdays  []string `yaml:"weekdays,omitempty"`   // 購入日の曜日 (mon, tue, ... または 月, 火, ...)
+
+	// 割り当てる値 (空の項目はデフォルトの値を使う)
+	AccountItem       string   `yaml:"account_item,omitempty"`        // 勘定科目
+	TaxCategory       string   `yaml:"tax_category,omitempty"`        // 税区分
+	Partner           string   `yaml:"partner,omitempty"`             // 取引先
+	Wallet            string   `yaml:"wallet,omitempty"`              // 決済口座
+	CreditAccountItem string   `yaml:"credit_account_item,omitempty"` // 貸方勘定科目 (マネーフォワード・弥生向け)
+	CreditSubAccount  string   `yaml:"credit_sub_account,omitempty"`  // 貸方補助科目 (マネーフォワード・弥生向け)
+	Tags              []string `yaml:"tags,omitempty"`                // メモタグ
+	Memo              string   `yaml:"memo,omitempty"`                // 備考のテンプレート (text/template)
+	BusinessRatio     *int     `yaml:"business_ratio,omitempty"`      // 家事按分の事業割合 (%)
+
+	itemRe   *regexp.Regexp
+	weekdays map[time.Weekday]bool
+	memo     *template.Template
+}
+
+// RuleSet ルールの設定ファイル
+type RuleSet struct {
+	Default Rule    `yaml:"default"` // どのルールにも当てはまらない場合や、項目が空の場合に使う値
+	Rules   []*Rule `yaml:"rules"`   // 上から順に評価し、最初に当てはまったものを使う
+}
+
+// defaultRule 設定ファイルがない場合に使う値
+var defaultRule = Rule{
+	Name:        "(default)",
+	AccountItem: "雑費",
+	TaxCategory: "課対仕入10%",
+	Wallet:      "現金",
+	Memo:        "{{summary .}}",
+}
+
+// defaultBusinessRatio 事業割合の指定がない場合は全額を経費にする
+const defaultBusinessRatio = 100
+
+// Path ルールの設定ファイルのデフォルトのパスを取得する
+func Path() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDirPath, "rules.yaml"), nil
+}
+
+// Load ルールの設定ファイルを読み込む
+// path が空の場合はデフォルトのパスから読み込み、ファイルがなければデフォルトのルールだけを返す
+func Load(path string) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if path == "" {
+		defaultPath, err := Path()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := os.Stat(defaultPath); !os.IsNotExist(err) {
+			path = defaultPath
+		}
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(b, rs); err != nil {
+			return nil, fmt.Errorf("ルールの設定ファイルの読み込みに失敗しました %s: %w", path, err)
+		}
+	}
+
+	rs.Default = rs.Default.merge(&defaultRule)
+	if rs.Default.Name == "" {
+		rs.Default.Name = defaultRule.Name
+	}
+	if err := rs.Default.compile(); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+
+	return rs, nil
+}
+
+// compile 正規表現・曜日・テンプレートを事前に解釈しておく
+func (r *Rule) compile() error {
+	if r.Item != "" {
+		re, err := regexp.Compile(r.Item)
+		if err != nil {
+			return fmt.Errorf("item の正規表現が不正です: %w", err)
+		}
+		r.itemRe = re
+	}
+
+	if len(r.Weekdays) > 0 {
+		r.weekdays = map[time.Weekday]bool{}
+		for _, name := range r.Weekdays {
+			weekday, ok := weekdayNames[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("曜日の指定が不正です: %s", name)
+			}
+			r.weekdays[weekday] = true
+		}
+	}
+
+	if r.Memo != "" {
+		tmpl, err := template.New(r.Name).Funcs(templateFuncs).Parse(r.Memo)
+		if err != nil {
+			return fmt.Errorf("memo のテンプレートが不正です: %w", err)
+		}
+		r.memo = tmpl
+	}
+
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return fmt.Errorf("min_amount (%d) が max_amount (%d) より大きいため、どの領収書にも当てはまりません", *r.MinAmount, *r.MaxAmount)
+	}
+
+	if r.BusinessRatio != nil && (*r.BusinessRatio < 0 || *r.BusinessRatio > 100) {
+		return fmt.Errorf("business_ratio は0から100の範囲で指定してください: %d", *r.BusinessRatio)
+	}
+
+	return nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"日": time.Sunday, "月": time.Monday, "火": time.Tuesday, "水": time.Wednesday,
+	"木": time.Thursday, "金": time.Friday, "土": time.Saturday,
+}
+
+// Match 領収書がルールの条件に当てはまるか
+func (r *Rule) Match(rc *receipt.Receipt) bool {
+	if r.Provider != "" && r.Provider != rc.Provider {
+		return false
+	}
+	if r.Type != "" && r.Type != rc.Type {
+		return false
+	}
+	if r.Vendor != "" && !strings.Contains(rc.Vendor, r.Vendor) {
+		return false
+	}
+	if r.itemRe != nil && !matchAny(r.itemRe, rc.Items) {
+		return false
+	}
+	// 金額の条件は円で指定するので、外貨建ての領収書には当てはめない
+	if (r.MinAmount != nil || r.MaxAmount != nil) && rc.Currency != "" {
+		return false
+	}
+	if r.MinAmount != nil && rc.Total < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && rc.Total > *r.MaxAmount {
+		return false
+	}
+	if r.weekdays != nil {
+		date, err := time.Parse("2006-01-02", rc.Date)
+		if err != nil || !r.weekdays[date.Weekday()] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(re *regexp.Regexp, items []string) bool {
+	for _, item := range items {
+		if re.MatchString(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Result 領収書にルールを適用した結果
+type Result struct {
+	Rule *Rule // 当てはまったルール (デフォルトの場合はRuleSet.Default)
+
+	AccountItem       string
+	TaxCategory       string
+	Partner           string
+	Wallet            string
+	CreditAccountItem string
+	CreditSubAccount  string
+	Tags              []string
+	Memo              string
+	BusinessRatio     int // 家事按分の事業割合 (%)
+	BusinessAmount    int // 事業割合を掛けた経費の金額
+}
+
+// Apply 最初に当てはまったルールを領収書に適用する
+func (rs *RuleSet) Apply(rc *receipt.Receipt) (*Result, error) {
+	rule := &rs.Default
+	for _, r := range rs.Rules {
+		if r.Match(rc) {
+			rule = r
+			break
+		}
+	}
+
+	merged := rule.merge(&rs.Default)
+	result := &Result{
+		Rule:              rule,
+		AccountItem:       merged.AccountItem,
+		TaxCategory:       merged.TaxCategory,
+		Partner:           merged.Partner,
+		Wallet:            merged.Wallet,
+		CreditAccountItem: merged.CreditAccountItem,
+		CreditSubAccount:  merged.CreditSubAccount,
+		Tags:              merged.Tags,
+		BusinessRatio:     defaultBusinessRatio,
+	}
+	if result.Partner == "" {
+		result.Partner = rc.Vendor
+	}
+	if result.CreditAccountItem == "" {
+		result.CreditAccountItem = result.Wallet
+	}
+	if merged.BusinessRatio != nil {
+		result.BusinessRatio = *merged.BusinessRatio
+	}
+	result.BusinessAmount = rc.Total * result.BusinessRatio / 100
+
+	if merged.memo != nil {
+		var buf bytes.Buffer
+		if err := merged.memo.Execute(&buf, rc); err != nil {
+			return nil, fmt.Errorf("%s: memo の生成に失敗しました: %w", rule.Name, err)
+		}
+		result.Memo = buf.String()
+	}
+
+	return result, nil
+}
+
+// merge 空の項目を fallback の値で埋める
+func (r *Rule) merge(fallback *Rule) Rule {
+	merged := *r
+	if merged.AccountItem == "" {
+		merged.AccountItem = fallback.AccountItem
+	}
+	if merged.TaxCategory == "" {
+		merged.TaxCategory = fallback.TaxCategory
+	}
+	if merged.Partner == "" {
+		merged.Partner = fallback.Partner
+	}
+	if merged.Wallet == "" {
+		merged.Wallet = fallback.Wallet
+	}
+	if merged.CreditAccountItem == "" {
+		merged.CreditAccountItem = fallback.CreditAccountItem
+	}
+	if merged.CreditSubAccount == "" {
+		merged.CreditSubAccount = fallback.CreditSubAccount
+	}
+	if merged.Tags == nil {
+		merged.Tags = fallback.Tags
+	}
+	if merged.Memo == "" {
+		merged.Memo = fallback.Memo
+		merged.memo = fallback.memo
+	}
+	if merged.BusinessRatio == nil {
+		merged.BusinessRatio = fallback.BusinessRatio
+	}
+	return merged
+}
@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+func TestMatch(t *testing.T) {
+	rs := load(t, `
+rules:
+  - name: provider
+    provider: ubereats
+    type: rides
+  - name: vendor
+    vendor: Amazon
+  - name: item
+    item: ^(本|雑誌)
+  - name: zero
+    min_amount: 0
+  - name: range
+    min_amount: 1000
+    max_amount: 3000
+  - name: weekend
+    weekdays: [sat, 日]
+`)
+	rules := map[string]*Rule{}
+	for _, r := range rs.Rules {
+		rules[r.Name] = r
+	}
+
+	for _, tt := range []struct {
+		name    string
+		rule    string
+		receipt *receipt.Receipt
+		want    bool
+	}{
+		{"provider and type", "provider", &receipt.Receipt{Provider: "ubereats", Type: "rides"}, true},
+		{"other type", "provider", &receipt.Receipt{Provider: "ubereats", Type: "eats"}, false},
+		{"other provider", "provider", &receipt.Receipt{Provider: "amazon", Type: "rides"}, false},
+		{"vendor contains", "vendor", &receipt.Receipt{Vendor: "Amazon.co.jp"}, true},
+		{"other vendor", "vendor", &receipt.Receipt{Vendor: "BOOK☆WALKER"}, false},
+		{"any item", "item", &receipt.Receipt{Items: []string{"ペン", "雑誌 3月号"}}, true},
+		{"no item", "item", &receipt.Receipt{}, false},
+		{"min_amount 0", "zero", &receipt.Receipt{Total: 0}, true},
+		{"below min_amount 0", "zero", &receipt.Receipt{Total: -100}, false},
+		{"min_amount boundary", "range", &receipt.Receipt{Total: 1000}, true},
+		{"max_amount boundary", "range", &receipt.Receipt{Total: 3000}, true},
+		{"below min_amount", "range", &receipt.Receipt{Total: 999}, false},
+		{"above max_amount", "range", &receipt.Receipt{Total: 3001}, false},
+		{"foreign currency", "range", &receipt.Receipt{Total: 2000, Currency: "USD"}, false},
+		{"no amount condition with foreign currency", "vendor", &receipt.Receipt{Vendor: "Amazon.com", Currency: "USD"}, true},
+		{"saturday", "weekend", &receipt.Receipt{Date: "2024-03-09"}, true},
+		{"sunday", "weekend", &receipt.Receipt{Date: "2024-03-10"}, true},
+		{"monday", "weekend", &receipt.Receipt{Date: "2024-03-11"}, false},
+		{"invalid date", "weekend", &receipt.Receipt{Date: "2024/03/09"}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules[tt.rule].Match(tt.receipt); got != tt.want {
+				t.Errorf("%s.Match(%+v) = %v, want %v", tt.rule, tt.receipt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	rs := load(t, `
+default:
+  account_item: 消耗品費
+  wallet: クレジットカード
+rules:
+  - name: small
+    vendor: Uber
+    max_amount: 3000
+    account_item: 会議費
+  - name: uber
+    vendor: Uber
+    account_item: 旅費交通費
+    business_ratio: 50
+  - vendor: Uber
+    account_item: 使われない
+`)
+
+	for _, tt := range []struct {
+		name        string
+		receipt     *receipt.Receipt
+		rule        string
+		accountItem string
+		amount      int
+	}{
+		{"first matching rule", &receipt.Receipt{Vendor: "Uber Eats", Total: 2000}, "small", "会議費", 2000},
+		{"next rule", &receipt.Receipt{Vendor: "Uber Eats", Total: 5000}, "uber", "旅費交通費", 2500},
+		{"default", &receipt.Receipt{Vendor: "Amazon", Total: 1000}, "(default)", "消耗品費", 1000},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rs.Apply(tt.receipt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Rule.Name != tt.rule {
+				t.Errorf("Rule = %s, want %s", got.Rule.Name, tt.rule)
+			}
+			if got.AccountItem != tt.accountItem {
+				t.Errorf("AccountItem = %s, want %s", got.AccountItem, tt.accountItem)
+			}
+			if got.BusinessAmount != tt.amount {
+				t.Errorf("BusinessAmount = %d, want %d", got.BusinessAmount, tt.amount)
+			}
+			if got.Wallet != "クレジットカード" || got.CreditAccountItem != "クレジットカード" {
+				t.Errorf("Wallet = %s, CreditAccountItem = %s, want クレジットカード", got.Wallet, got.CreditAccountItem)
+			}
+		})
+	}
+
+	if name := rs.Rules[2].Name; name != "rules[2]" {
+		t.Errorf("unnamed rule = %s, want rules[2]", name)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, yaml := range []string{
+		"rules:\n  - min_amount: 3000\n    max_amount: 1000\n",
+		"rules:\n  - business_ratio: 101\n",
+		"rules:\n  - item: \"(\"\n",
+		"rules:\n  - weekdays: [holiday]\n",
+	} {
+		if _, err := Load(writeRules(t, yaml)); err == nil {
+			t.Errorf("Load(%q): want error", yaml)
+		}
+	}
+}
+
+func load(t *testing.T, yaml string) *RuleSet {
+	t.Helper()
+	rs, err := Load(writeRules(t, yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func writeRules(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,27 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// templateFuncs 備考のテンプレートで使える関数
+var templateFuncs = map[string]any{
+	"join":    strings.Join,
+	"summary": summary,
+}
+
+// summary 取得元と商品名から備考を作る (例: bookwalker 書籍A 他2件)
+func summary(r *receipt.Receipt) string {
+	parts := []string{r.Provider}
+	switch len(r.Items) {
+	case 0:
+	case 1:
+		parts = append(parts, r.Items[0])
+	default:
+		parts = append(parts, fmt.Sprintf("%s 他%d件", r.Items[0], len(r.Items)-1))
+	}
+	return strings.Join(parts, " ")
+}
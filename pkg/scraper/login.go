@@ -0,0 +1,275 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/prompt"
+	"github.com/chromedp/chromedp"
+	"github.com/fatih/color"
+)
+
+const (
+	// defaultLoginTimeout ログイン完了を待つ時間のデフォルト (画像認証を手動で操作する時間を含む)
+	defaultLoginTimeout = 5 * time.Minute
+	// pageTimeout ページの要素の表示を待つ時間
+	pageTimeout = 30 * time.Second
+	// maxLoginAttempts ログインを試す最大の回数
+	maxLoginAttempts = 3
+	// defaultCaptcha 画像認証の要素のデフォルトのセレクタ (reCAPTCHAのチェックボックスと画像選択)
+	defaultCaptcha = `iframe[src*="recaptcha/api2/anchor"], iframe[src*="recaptcha/api2/bframe"], iframe[src*="recaptcha/enterprise"]`
+)
+
+var (
+	// ErrLoginFailed ログインフォームにエラーが表示された (メールアドレスかパスワードが違う)
+	ErrLoginFailed = errors.New("ログインに失敗しました")
+	// ErrLoginTimeout ログインが時間内に完了しなかった
+	ErrLoginTimeout = errors.New("ログインがタイムアウトしました")
+	// ErrSessionExpired 途中でセッションが切れてログインページに戻された
+	ErrSessionExpired = errors.New("セッションが切れました")
+)
+
+// ページの状態
+const (
+	stateSuccess = "success" // ログイン後の要素が表示されている
+	stateError   = "error"   // ログインのエラーメッセージが表示されている
+	stateCaptcha = "captcha" // 画像認証が表示されている
+	stateForm    = "form"    // ログインフォームが表示されている
+	stateReady   = "ready"   // 待っていた要素が表示されている
+)
+
+// pageState ページの状態を判定するJavaScriptの結果
+type pageState struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// stateScript ページの状態を判定するJavaScript
+// ready が空でなければ ready の要素を、空ならログイン後の要素を最初に探す
+const stateScript = `(() => {
+	const visible = (selector) => {
+		if (!selector) return null;
+		for (const e of document.querySelectorAll(selector)) {
+			if (e.closest('.grecaptcha-badge')) continue;
+			const rect = e.getBoundingClientRect();
+			const style = getComputedStyle(e);
+			if (rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none') return e;
+		}
+		return null;
+	};
+	const s = %s;
+	if (s.ready && document.querySelector(s.ready)) return {state: 'ready'};
+	if (visible(s.success)) return {state: 'success'};
+	const error = visible(s.error);
+	if (error && error.innerText.trim() !== '') return {state: 'error', message: error.innerText.trim()};
+	if (visible(s.captcha)) return {state: 'captcha'};
+	if (document.querySelector(s.form)) return {state: 'form'};
+	return {state: ''};
+})()`
+
+// checkState ページの状態を判定する
+// ready: 待っている要素のセレクタ (ログイン以外のページで、ログインページに戻されていないか確認する場合)
+func checkState(ctx context.Context, site *Site, ready string) (*pageState, error) {
+	selectors, err := json.Marshal(map[string]string{
+		"ready":   ready,
+		"success": site.Login.Success,
+		"error":   site.Login.Error,
+		"captcha": site.Login.Captcha,
+		"form":    site.Login.EmailField,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state := &pageState{}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(stateScript, selectors), state)); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// waitState done が true を返す状態になるまでページの状態を確認する
+// 時間内にならなかった場合は最後の状態と ErrLoginTimeout を返す
+func waitState(ctx context.Context, site *Site, ready string, timeout time.Duration, done func(*pageState) bool) (*pageState, error) {
+	deadline := time.Now().Add(timeout)
+	last := &pageState{}
+	for {
+		// ページの遷移中は評価に失敗することがあるので、次の確認まで待つ
+		if state, err := checkState(ctx, site, ready); err == nil {
+			last = state
+			if done(state) {
+				return state, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return last, ErrLoginTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// login ログイン情報を取得してログインし、セッションを保存する
+// ログインに失敗した場合は、端末から実行していればログイン情報を入力し直させて再試行する
+func login(ctx context.Context, site *Site, creds credential.Options) error {
+	fmt.Printf("Chromeを自動操作して%sにログインします。\n", site.DisplayName)
+	resolver := credential.New(site.ID, site.Login.URL, creds)
+	c, err := resolver.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := Login(ctx, site, c.Email, c.Password)
+		if err == nil {
+			// reCAPTCHAが入ることがあるのでそれを待機する
+			if site.Login.Submit == "" {
+				fmt.Println("ログインボタンを押してください。(reCAPTCHAが表示されたら手動で操作して完了してください)")
+			}
+			err = WaitLogin(ctx, site)
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= maxLoginAttempts || !prompt.IsTerminal() {
+			return err
+		}
+
+		fmt.Println(color.RedString("×"), err)
+		if !errors.Is(err, ErrLoginFailed) {
+			if !prompt.Confirm("もう一度ログインしますか?") {
+				return err
+			}
+			continue
+		}
+
+		// エラーメッセージはセレクタで推測しているだけなので、ログイン情報が間違っているか確かめてから削除する
+		// ヘルパーのログイン情報は利用者のパスワードマネージャーにあるので削除しない
+		if !prompt.ConfirmNo("ログイン情報が間違っていますか? 保存したログイン情報を削除して入力し直します") {
+			return err
+		}
+		if err := resolver.Reject(c); err != nil {
+			fmt.Println(color.YellowString("!"), err)
+		}
+		if c, err = resolver.Prompt(); err != nil {
+			return err
+		}
+	}
+
+	if err := resolver.Approve(ctx, c); err != nil {
+		fmt.Println(color.YellowString("!"), err)
+	}
+
+	// 次回ログインを省略できるようにCookieを保存する
+	if err := saveSession(ctx, site, creds.Account); err != nil {
+		fmt.Println(color.YellowString("!"), "セッションを保存できませんでした:", err)
+	}
+	return nil
+}
+
+// relogin セッションが切れて失敗した処理を、ログインし直してから1度だけやり直す
+func relogin(ctx context.Context, site *Site, creds credential.Options, fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrSessionExpired) {
+		return err
+	}
+
+	fmt.Println(color.YellowString("!"), "セッションが切れたため、ログインし直します。")
+	if err := login(ctx, site, creds); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// Login Chromeを自動操作してログインフォームに入力する
+// ログインボタンのセレクタが定義されている場合はボタンも押す
+func Login(ctx context.Context, site *Site, email string, password string) error {
+	actions := []chromedp.Action{
+		chromedp.Navigate(site.Login.URL),                                       // ログインページに遷移
+		chromedp.WaitVisible(site.Login.EmailField, chromedp.ByQuery),           // メールアドレスの入力欄が表示されるまで待機
+		chromedp.Clear(site.Login.EmailField, chromedp.ByQuery),                 // 再試行のときは前回の入力を消す
+		chromedp.SendKeys(site.Login.EmailField, email, chromedp.ByQuery),       // メールアドレスを入力
+		chromedp.Clear(site.Login.PasswordField, chromedp.ByQuery),              // 再試行のときは前回の入力を消す
+		chromedp.SendKeys(site.Login.PasswordField, password, chromedp.ByQuery), // パスワードを入力
+	}
+	if site.Login.Submit != "" {
+		actions = append(actions, chromedp.Click(site.Login.Submit, chromedp.ByQuery))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pageTimeout)
+	defer cancel()
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%sのログインフォームが表示されませんでした: %w", site.DisplayName, ErrLoginTimeout)
+		}
+		return fmt.Errorf("%sのログインフォームに入力できませんでした: %w", site.DisplayName, err)
+	}
+	return nil
+}
+
+// WaitLogin ログイン完了まで待機する
+// エラーメッセージが表示された場合は ErrLoginFailed、時間内に完了しなかった場合は ErrLoginTimeout を返す
+func WaitLogin(ctx context.Context, site *Site) error {
+	captchaShown := false
+	state, err := waitState(ctx, site, "", site.Login.Timeout, func(s *pageState) bool {
+		if s.State == stateCaptcha && !captchaShown {
+			captchaShown = true
+			fmt.Println(color.YellowString("!"), "画像認証が表示されました。ブラウザで操作して完了してください。")
+		}
+		return s.State == stateSuccess || s.State == stateError
+	})
+	if err != nil {
+		if errors.Is(err, ErrLoginTimeout) && (state.State == stateCaptcha || captchaShown) {
+			return fmt.Errorf("画像認証が完了しませんでした (%s): %w", site.Login.Timeout, err)
+		}
+		if errors.Is(err, ErrLoginTimeout) {
+			return fmt.Errorf("%sのログインが %s 以内に完了しませんでした: %w", site.DisplayName, site.Login.Timeout, err)
+		}
+		return err
+	}
+
+	if state.State == stateError {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, strings.Join(strings.Fields(state.Message), " "))
+	}
+	return nil
+}
+
+// waitPage ページに要素が表示されるまで待機する
+// ログインページに戻された場合は ErrSessionExpired を返す
+func waitPage(ctx context.Context, site *Site, selector string) error {
+	state, err := waitState(ctx, site, selector, pageTimeout, func(s *pageState) bool {
+		return s.State == stateReady || s.State == stateForm
+	})
+	if err != nil {
+		if errors.Is(err, ErrLoginTimeout) {
+			return fmt.Errorf("ページの表示がタイムアウトしました (%s)", selector)
+		}
+		return err
+	}
+	if state.State == stateForm {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+// checkSession 表示中のページがログインページでないか確認する
+// ログインページに戻されている場合は ErrSessionExpired を返す
+func checkSession(ctx context.Context, site *Site) error {
+	state, err := checkState(ctx, site, "")
+	if err != nil {
+		return err
+	}
+	if state.State == stateForm {
+		return ErrSessionExpired
+	}
+	return nil
+}
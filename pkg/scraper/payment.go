@@ -0,0 +1,115 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/fatih/color"
+)
+
+// 購入履歴の支払の種類
+const (
+	PaymentPurchase     = "purchase"     // 商品の購入 (常に取得する)
+	PaymentCoin         = "coin"         // コインのチャージ・購入
+	PaymentPoint        = "point"        // ポイントやコインだけで支払った購入 (現金の支払なし)
+	PaymentSubscription = "subscription" // 月額・読み放題などの定期購入
+)
+
+// PaymentTypes --include で指定できる支払の種類
+func PaymentTypes() []string {
+	return []string{PaymentCoin, PaymentPoint, PaymentSubscription}
+}
+
+// ValidatePaymentTypes --include に指定した支払の種類を確認する
+func ValidatePaymentTypes(types []string) error {
+	for _, t := range types {
+		if !slices.Contains(PaymentTypes(), t) && t != PaymentPurchase {
+			return fmt.Errorf("支払の種類が不正です: %s (指定できる種類: %s)", t, strings.Join(PaymentTypes(), ", "))
+		}
+	}
+	return nil
+}
+
+// paymentType サイト定義の rows が返した支払の種類 (空の場合は purchase)
+func paymentType(t string) string {
+	if t == "" {
+		return PaymentPurchase
+	}
+	return strings.ToLower(t)
+}
+
+// selectReceipts 取得する支払の種類の領収書だけを返し、取得しない領収書は理由をレポートに記録する
+// 購入 (purchase) と、領収書のページがあり支払額が0より大きい行は常に取得し、それ以外は include に指定した種類だけを取得する
+func selectReceipts(receipts []*receipt.Receipt, include []string, report *receipt.Report) []*receipt.Receipt {
+	selected := make([]*receipt.Receipt, 0, len(receipts))
+	for _, r := range receipts {
+		if r.PaymentType == PaymentPurchase || (r.URL != "" && r.Total > 0) || slices.Contains(include, r.PaymentType) {
+			selected = append(selected, r)
+			continue
+		}
+
+		reason := fmt.Sprintf("%s の支払は取得しない設定です (--include %s で取得できます)", paymentLabel(r.PaymentType), r.PaymentType)
+		if !slices.Contains(PaymentTypes(), r.PaymentType) {
+			reason = fmt.Sprintf("不明な支払の種類です: %s", r.PaymentType)
+		}
+		report.Skip(r, reason)
+	}
+	return selected
+}
+
+// printSkipped 取得しなかった行の件数を支払の種類ごとに表示する
+func printSkipped(skipped []*receipt.Skipped) {
+	if len(skipped) == 0 {
+		return
+	}
+	counts := map[string]int{}
+	types := []string{}
+	for _, s := range skipped {
+		if counts[s.PaymentType] == 0 {
+			types = append(types, s.PaymentType)
+		}
+		counts[s.PaymentType]++
+	}
+	for _, t := range types {
+		fmt.Printf("%s %sの支払 %d件を取得しませんでした (--include %s で取得できます)\n",
+			color.YellowString("!"), paymentLabel(t), counts[t], t)
+	}
+}
+
+// paymentLabel 画面表示用の支払の種類の名前
+func paymentLabel(t string) string {
+	switch t {
+	case PaymentPurchase:
+		return "購入"
+	case PaymentCoin:
+		return "コイン購入"
+	case PaymentPoint:
+		return "ポイント・コインのみ"
+	case PaymentSubscription:
+		return "定期購入"
+	}
+	return t
+}
+
+// uniqueIDs 領収書のページがない行で同じIDになったものに、出てきた順に -2, -3 を付けて区別する
+func uniqueIDs(receipts []*receipt.Receipt) {
+	seen := map[string]int{}
+	for _, r := range receipts {
+		if r.URL != "" {
+			continue
+		}
+		seen[r.ID]++
+		if n := seen[r.ID]; n > 1 {
+			r.ID = fmt.Sprintf("%s-%d", r.ID, n)
+		}
+	}
+}
+
+// saveMetadata 領収書のページがない支払の購入情報だけを保存する (PDFは保存しない)
+func saveMetadata(r *receipt.Receipt, outputDir string) error {
+	_, err := receipt.WriteSidecar(filepath.Join(outputDir, r.ID+".pdf"), r)
+	return err
+}
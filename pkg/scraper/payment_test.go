@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+func TestSelectReceipts(t *testing.T) {
+	receipts := []*receipt.Receipt{
+		{ID: "1", PaymentType: PaymentPurchase, URL: "https://example.com/1", Total: 1100},
+		{ID: "2", PaymentType: PaymentCoin, URL: "https://example.com/2", Total: 1000}, // 領収書のある有料の行は常に取得する
+		{ID: "3", PaymentType: PaymentCoin, Total: 500},
+		{ID: "4", PaymentType: PaymentPoint, URL: "https://example.com/4"},
+		{ID: "5", PaymentType: PaymentSubscription, Total: 836},
+	}
+
+	tests := []struct {
+		include []string
+		want    []string
+	}{
+		{nil, []string{"1", "2"}},
+		{[]string{PaymentCoin}, []string{"1", "2", "3"}},
+		{[]string{PaymentPoint, PaymentSubscription}, []string{"1", "2", "4", "5"}},
+	}
+	for _, tt := range tests {
+		report := receipt.NewReport("test", "202401", "")
+		got := selectReceipts(receipts, tt.include, report)
+		ids := []string{}
+		for _, r := range got {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("include %v: got %v, want %v", tt.include, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("include %v: got %v, want %v", tt.include, ids, tt.want)
+				break
+			}
+		}
+		if len(report.Skipped) != len(receipts)-len(tt.want) {
+			t.Errorf("include %v: skipped %d, want %d", tt.include, len(report.Skipped), len(receipts)-len(tt.want))
+		}
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	r := row{Type: PaymentCoin, Date: "2024/01/05", Total: "1,000円", Items: []string{"コイン"}}
+	site := &Site{}
+	id, err := site.receiptID(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts := []*receipt.Receipt{{ID: id}, {ID: id}, {ID: "123", URL: "https://example.com/123"}, {ID: id}}
+	uniqueIDs(receipts)
+
+	want := []string{id, id + "-2", "123", id + "-3"}
+	for i, r := range receipts {
+		if r.ID != want[i] {
+			t.Errorf("receipts[%d].ID = %s, want %s", i, r.ID, want[i])
+		}
+	}
+}
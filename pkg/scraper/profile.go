@@ -0,0 +1,146 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/browser"
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile プロファイルを指定しない場合に使うプロファイルの名前 (profiles.yaml にあれば使う)
+const DefaultProfile = "default"
+
+// Profile 領収書を保存するときの宛名と印刷設定
+type Profile struct {
+	Name      string `yaml:"-"`
+	Addressee string `yaml:"addressee"` // 領収書の宛名 (例: 株式会社Example)
+
+	browser.PrintOptions `yaml:",inline"`
+}
+
+// profilesFile プロファイルの設定ファイル
+type profilesFile struct {
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// ProfilesPath プロファイルの設定ファイルのパス
+func ProfilesPath() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDirPath, "profiles.yaml"), nil
+}
+
+// LoadProfile 設定ディレクトリの profiles.yaml からプロファイルを読み込む
+// name が空の場合は default プロファイル、それもなければ宛名なし・A4の設定を返す
+func LoadProfile(name string) (*Profile, error) {
+	path, err := ProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file := &profilesFile{}
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(b, file); err != nil {
+			return nil, fmt.Errorf("プロファイルの読み込みに失敗しました %s: %w", path, err)
+		}
+	}
+
+	if name == "" {
+		name = DefaultProfile
+	}
+	profile, ok := file.Profiles[name]
+	if !ok {
+		if name == DefaultProfile {
+			return &Profile{Name: DefaultProfile}, nil
+		}
+		names := make([]string, 0, len(file.Profiles))
+		for n := range file.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("プロファイルが見つかりません: %s (%s: %s)", name, path, strings.Join(names, ", "))
+	}
+
+	profile.Name = name
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("プロファイル %s: %w", name, err)
+	}
+	return profile, nil
+}
+
+// printOptions サイト定義のCSSを加えた印刷設定
+func (p *Profile) printOptions(site *Site) *browser.PrintOptions {
+	opts := p.PrintOptions
+	opts.CSS = strings.TrimSpace(site.Receipt.CSS + "\n" + p.CSS)
+	return &opts
+}
+
+// addresseeScript 宛名を入力するJavaScript
+// サイトの宛名の入力欄に入力して反映ボタンを押す。領収書の文字を直接書き換えることはしない
+const addresseeScript = `((s, name) => {
+	const field = s.field && document.querySelector(s.field);
+	if (!field) {
+		return '';
+	}
+	field.value = name;
+	field.dispatchEvent(new Event('input', {bubbles: true}));
+	field.dispatchEvent(new Event('change', {bubbles: true}));
+	const button = s.submit && document.querySelector(s.submit);
+	if (button) {
+		button.click();
+		return 'submitted';
+	}
+	return 'field';
+})(%s, %s)`
+
+// fillAddressee 領収書ページの宛名の入力欄に宛名を入力する
+// サイト定義に宛名の入力欄がないか、ページに入力欄が見つからない場合はエラーを返す
+func fillAddressee(ctx context.Context, site *Site, id string, addressee string) error {
+	if site.Receipt.Addressee.Field == "" {
+		return fmt.Errorf("%s の領収書は宛名を入力できません。プロファイルの addressee を空にしてください", site.DisplayName)
+	}
+	selectors, err := json.Marshal(map[string]string{
+		"field":  site.Receipt.Addressee.Field,
+		"submit": site.Receipt.Addressee.Submit,
+	})
+	if err != nil {
+		return err
+	}
+	name, err := json.Marshal(addressee)
+	if err != nil {
+		return err
+	}
+
+	var result string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(addresseeScript, selectors, name), &result)); err != nil {
+		return fmt.Errorf("宛名を入力できませんでした: %w", err)
+	}
+	switch result {
+	case "":
+		return fmt.Errorf("領収書に宛名を入力できる欄がありません: %s", id)
+	case "submitted":
+		// 宛名を反映するとページを読み込み直すので、読み込みが始まるのを待ってからもう一度領収書の表示を待つ
+		if err := chromedp.Run(ctx, chromedp.Sleep(time.Second)); err != nil {
+			return err
+		}
+		if err := waitPage(ctx, site, site.Receipt.Wait); err != nil {
+			return err
+		}
+	}
+	return nil
+}
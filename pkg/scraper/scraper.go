@@ -0,0 +1,345 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/browser"
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/invoice"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"github.com/chromedp/chromedp"
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Options 領収書を取得するときのオプション
+type Options struct {
+	Credentials credential.Options // ログイン情報の取得方法
+	Profile     *Profile           // 領収書の宛名と印刷設定 (nil の場合は宛名なし・A4)
+	Include     []string           // 購入 (purchase) 以外に取得する支払の種類 (coin, point, subscription)
+}
+
+// Run サイト定義に従ってログインし、期間内の領収書をダウンロードする
+// after, before: 検索範囲の年月 (format: 202401)
+func Run(ctx context.Context, site *Site, opts *Options, after, before, outputDir string) error {
+	if err := ValidatePaymentTypes(opts.Include); err != nil {
+		return err
+	}
+	creds := opts.Credentials
+
+	// 取得対象の年月範囲を生成
+	targetDate, err := generatePeriods(after, before, site.History.Period)
+	if err != nil {
+		return err
+	}
+
+	// chromedpの設定
+	ctx, cancel := browser.NewContext(ctx)
+	defer cancel()
+
+	// 先にブラウザを起動しておく (最初の操作にタイムアウトを付けるとブラウザごと閉じてしまうため)
+	if err := chromedp.Run(ctx); err != nil {
+		return fmt.Errorf("Chromeを起動できませんでした: %w", err)
+	}
+
+	// 保存したセッションが有効ならログインを省略する
+	restored, err := restoreSession(ctx, site, creds.Account)
+	if err != nil {
+		fmt.Println(color.YellowString("!"), err)
+	}
+	if restored {
+		fmt.Printf("保存したセッションで%sにログインしました。\n", site.DisplayName)
+	} else if err := login(ctx, site, creds); err != nil {
+		return err
+	}
+
+	// 購入履歴ページを開いて各領収書のURLと購入情報を取得
+	fmt.Println("領収書のURLを取得します")
+	receipts := []*receipt.Receipt{}
+	gerURLProgressBar := progressbar.Default(int64(len(targetDate)))
+	for _, date := range targetDate {
+
+		// 対象期間の領収書を1ページ目から取得する
+		page := site.History.FirstPage
+		for {
+			var rs []*receipt.Receipt
+			err := relogin(ctx, site, creds, func() error {
+				var err error
+				rs, err = GetReceipts(ctx, site, date, page)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(rs) == 0 {
+				// そのページが存在しなくてもURLにはアクセスできるが、領収書が存在しないページになる
+				// 取得できたURLが0件になった場合、その期間の領収書URLは全て取得しているはずなのでループを抜け次の期間へ進める
+				break
+			}
+
+			receipts = append(receipts, rs...)
+			page++
+		}
+
+		gerURLProgressBar.Add(1)
+	}
+
+	// 年単位の購入履歴には期間外の領収書も含まれるので購入日で絞り込む
+	if site.History.Period == "year" {
+		receipts = receipt.Filter(receipts, periodStart(after), periodEnd(after, before))
+	}
+
+	// 領収書のページがない同じ内容の行 (同じ日の同じ額のコインのチャージなど) のIDを区別する
+	uniqueIDs(receipts)
+
+	// 取得しない支払の種類の行を除き、理由をレポートに記録する
+	report := receipt.NewReport(site.Name, after, before)
+	receipts = selectReceipts(receipts, opts.Include, report)
+	fmt.Println("領収書のURLを取得しました 件数:", len(receipts))
+	printSkipped(report.Skipped)
+
+	// 領収書をダウンロード
+	fmt.Println("領収書をダウンロードします")
+	downloadProgressBar := progressbar.Default(int64(len(receipts)))
+	for _, r := range receipts {
+		err := relogin(ctx, site, creds, func() error {
+			if r.URL == "" {
+				// 領収書のページがない支払は購入情報だけを保存する
+				return saveMetadata(r, outputDir)
+			}
+			return DownloadReceipt(ctx, site, opts.Profile, r, outputDir)
+		})
+		if err != nil {
+			return err
+		}
+		if r.URL == "" {
+			report.AddMetadataOnly(r)
+		} else {
+			report.Add(r)
+		}
+		downloadProgressBar.Add(1)
+	}
+
+	reportPath, err := report.Write(outputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Println("レポートを保存しました:", reportPath)
+
+	return nil
+}
+
+// row 購入履歴ページの1行分の情報
+type row struct {
+	URL           string   `json:"url"`
+	ID            string   `json:"id"`
+	Date          string   `json:"date"`
+	Total         string   `json:"total"`
+	Currency      string   `json:"currency"`
+	Cash          string   `json:"cash"`
+	PaymentMethod string   `json:"paymentMethod"`
+	Coin          string   `json:"coin"`
+	Point         string   `json:"point"`
+	Type          string   `json:"type"`
+	Items         []string `json:"items"`
+}
+
+// GetReceipts 購入履歴ページから領収書のURLと購入情報を取得する
+// ログインページに戻された場合は ErrSessionExpired を返す
+// date: YYYYMM
+// page: ページ
+func GetReceipts(ctx context.Context, site *Site, date string, page int) ([]*receipt.Receipt, error) {
+	// 購入履歴ページのURL
+	historyURL, err := site.HistoryURL(date, page)
+	if err != nil {
+		return nil, err
+	}
+
+	// 購入履歴ページから領収書URLと購入情報を取得
+	if err := chromedp.Run(ctx, chromedp.Navigate(historyURL)); err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt URLs: %w", err)
+	}
+	// ログインページでは領収書が0件になり、その期間の取得を終えてしまうので先に確認する
+	if err := checkSession(ctx, site); err != nil {
+		return nil, err
+	}
+	var rows []row
+	if err := chromedp.Run(ctx, chromedp.Evaluate(site.History.Rows, &rows)); err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt URLs: %w", err)
+	}
+
+	receipts := make([]*receipt.Receipt, 0, len(rows))
+	for _, row := range rows {
+		id, err := site.receiptID(row)
+		if err != nil {
+			return nil, err
+		}
+		total, currency := site.parseMoney(row.Total, row.Currency)
+		// コイン・ポイントの利用額も支払額と同じ通貨で表示される (例: $1.50)
+		coin, _ := site.parseMoney(row.Coin, row.Currency)
+		point, _ := site.parseMoney(row.Point, row.Currency)
+		// 利用額をマイナスで表示するサイトもあるので正の数にそろえる
+		coin, point = abs(coin), abs(point)
+		// 現金の支払額が別に表示されない場合は、支払合計金額からコイン・ポイントの利用額を引いた額にする
+		cash := total - coin - point
+		if row.Cash != "" {
+			cash, _ = site.parseMoney(row.Cash, row.Currency)
+		}
+		if cash < 0 {
+			cash = 0
+		}
+		receipts = append(receipts, &receipt.Receipt{
+			Provider:      site.Name,
+			ID:            id,
+			Vendor:        site.Vendor,
+			URL:           row.URL,
+			Date:          receipt.NormalizeDate(row.Date),
+			Region:        site.Region,
+			Total:         total,
+			Currency:      currency,
+			PaymentMethod: row.PaymentMethod,
+			PaymentType:   paymentType(row.Type),
+			CashAmount:    cash,
+			CoinUsage:     coin,
+			PointUsage:    point,
+			Items:         row.Items,
+		})
+	}
+	return receipts, nil
+}
+
+// DownloadReceipt 領収書ページを開き、プロファイルの宛名を入力してPDFとメタデータを保存する
+// ログインページに戻された場合は ErrSessionExpired を返す
+func DownloadReceipt(ctx context.Context, site *Site, profile *Profile, r *receipt.Receipt, outputDir string) error {
+	if profile == nil {
+		profile = &Profile{}
+	}
+
+	if err := chromedp.Run(ctx, chromedp.Navigate(r.URL)); err != nil {
+		return fmt.Errorf("failed to download receipt: %w", err)
+	}
+	// 領収書の要素が表示されるまで待機
+	if err := waitPage(ctx, site, site.Receipt.Wait); err != nil {
+		return err
+	}
+	if profile.Addressee != "" {
+		if err := fillAddressee(ctx, site, r.ID, profile.Addressee); err != nil {
+			return err
+		}
+		r.Addressee = profile.Addressee
+	}
+
+	var pdfBuf []byte
+	var receiptText string
+	if err := chromedp.Run(ctx,
+		chromedp.Text(`body`, &receiptText, chromedp.ByQuery), // 登録番号を探すために領収書のテキストを取得
+		browser.PrintToPDF(&pdfBuf, profile.printOptions(site)),
+	); err != nil {
+		return fmt.Errorf("failed to download receipt: %w", err)
+	}
+
+	// 領収書のIDをファイル名にする
+	// ダウンロードしたPDFを保存
+	fileName := fmt.Sprintf("%s.pdf", r.ID)
+	pdfPath, err := receipt.WritePDF(outputDir, fileName, pdfBuf)
+	if err != nil {
+		return err
+	}
+
+	// 適格請求書発行事業者の登録番号を領収書から探す
+	if number, err := invoice.Extract(receiptText); err == nil {
+		r.RegistrationNumber = number
+	} else {
+		fmt.Println(color.YellowString("!"), "領収書に登録番号が見つかりません:", r.ID)
+	}
+
+	// 購入情報をPDFと並べて保存
+	r.PDFFile = fileName
+	if _, err := receipt.WriteSidecar(pdfPath, r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// generatePeriods 年月範囲の文字列のスライスを作る
+// period が year の場合は各年の1月だけにする (例: ["202301", "202401"])
+func generatePeriods(after, before, period string) ([]string, error) {
+	yearMonths, err := generateYearMonths(after, before)
+	if err != nil {
+		return nil, err
+	}
+	if period != "year" {
+		return yearMonths, nil
+	}
+
+	years := []string{}
+	for _, ym := range yearMonths {
+		if len(years) == 0 || years[len(years)-1][:4] != ym[:4] {
+			years = append(years, ym[:4]+"01")
+		}
+	}
+	return years, nil
+}
+
+// periodStart 開始年月の1日 (format: 2024-01-01)
+func periodStart(after string) string {
+	return after[:4] + "-" + after[4:] + "-01"
+}
+
+// periodEnd 終了年月の末日 (format: 2024-01-31)
+func periodEnd(after, before string) string {
+	if before == "" {
+		before = after
+	}
+	end, err := time.Parse("200601", before)
+	if err != nil {
+		return ""
+	}
+	return end.AddDate(0, 1, -1).Format("2006-01-02")
+}
+
+// generateYearMonths 年月範囲の文字列のスライスを作る
+func generateYearMonths(after, before string) ([]string, error) {
+	// after: "202301"
+	// before: "202312"
+	// 例:[ "202301", "202302", "202303", ... , "202312"]
+	// beforeが空文字の場合はafterの年月のみを取得対象とする
+
+	startDate, err := time.Parse("200601", after)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing start date: %v", err)
+	}
+
+	if before == "" {
+		return []string{startDate.Format("200601")}, nil
+	}
+
+	endDate, err := time.Parse("200601", before)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing end date: %v", err)
+	}
+
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date must be equal to or after start date")
+	}
+
+	yearMonths := []string{}
+	current := startDate
+	for !current.After(endDate) {
+		yearMonths = append(yearMonths, current.Format("200601"))
+		current = current.AddDate(0, 1, 0)
+	}
+
+	return yearMonths, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,86 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/browser"
+	"github.com/JINZO631/freeedom/pkg/credential"
+	"github.com/JINZO631/freeedom/pkg/secret"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// sessionCheckTimeout 保存したセッションでログイン済みか確認するときに待つ時間
+const sessionCheckTimeout = 15 * time.Second
+
+// Forget サイトの保存したログイン情報とセッションを削除する
+func Forget(siteName string) error {
+	if err := credential.Forget(siteName); err != nil {
+		return err
+	}
+	path, err := secret.SitePath("cookies", siteName, "")
+	if err != nil {
+		return err
+	}
+	if err := secret.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(filepath.Dir(path), siteName))
+}
+
+// restoreSession 保存したCookieをブラウザに設定し、ログイン済みの状態になるか確認する
+// セッションはアカウントごとに保存する
+func restoreSession(ctx context.Context, site *Site, account string) (bool, error) {
+	path, err := secret.SitePath("cookies", site.ID, account)
+	if err != nil {
+		return false, err
+	}
+	var cookies []*network.Cookie
+	if err := secret.LoadJSON(path, &cookies); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := chromedp.Run(ctx,
+		browser.SetCookies(cookies),
+		chromedp.Navigate(site.Login.URL),
+	); err != nil {
+		return false, fmt.Errorf("保存したセッションを復元できませんでした: %w", err)
+	}
+	return loggedIn(ctx, site)
+}
+
+// loggedIn ログイン後の要素とログインフォームのどちらが表示されるかでログイン済みか判定する
+func loggedIn(ctx context.Context, site *Site) (bool, error) {
+	state, err := waitState(ctx, site, "", sessionCheckTimeout, func(s *pageState) bool {
+		return s.State == stateSuccess || s.State == stateForm
+	})
+	if err != nil {
+		if errors.Is(err, ErrLoginTimeout) {
+			return false, nil
+		}
+		return false, err
+	}
+	return state.State == stateSuccess, nil
+}
+
+// saveSession ログインしたブラウザのCookieを暗号化して保存する
+func saveSession(ctx context.Context, site *Site, account string) error {
+	path, err := secret.SitePath("cookies", site.ID, account)
+	if err != nil {
+		return err
+	}
+	var cookies []*network.Cookie
+	if err := chromedp.Run(ctx, browser.GetCookies(&cookies)); err != nil {
+		return err
+	}
+	return secret.SaveJSON(path, cookies)
+}
@@ -0,0 +1,251 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+	"gopkg.in/yaml.v3"
+)
+
+// Site ログイン → 購入履歴の一覧 → 領収書ページの印刷 という流れで領収書を取得するサイトの定義
+type Site struct {
+	Name        string `yaml:"name"`         // 取得元の名前 (領収書のメタデータの provider)
+	DisplayName string `yaml:"display_name"` // 画面表示用の名前
+	Vendor      string `yaml:"vendor"`       // 領収書の発行者
+	ID          string `yaml:"id"`           // ログイン情報とセッションを保存する名前 (省略時は name。同じ取得元の別のストアを分ける場合に指定する)
+	Region      string `yaml:"region"`       // ストアの地域 (領収書のメタデータの region。例: jp, global)
+	Currency    string `yaml:"currency"`     // 金額に通貨記号がない場合の通貨コード (省略時は日本円)
+
+	Login struct {
+		URL           string `yaml:"url"`            // ログインページのURL
+		EmailField    string `yaml:"email_field"`    // メールアドレスの入力欄のセレクタ
+		PasswordField string `yaml:"password_field"` // パスワードの入力欄のセレクタ
+		Submit        string `yaml:"submit"`         // ログインボタンのセレクタ (空の場合は利用者が押す)
+		Success       string `yaml:"success"`        // ログイン後に表示される要素のセレクタ
+		Error         string `yaml:"error"`          // ログインに失敗したときに表示されるエラーメッセージのセレクタ
+		Captcha       string `yaml:"captcha"`        // 画像認証の要素のセレクタ (空の場合は reCAPTCHA)
+
+		// Timeout ログイン完了を待つ時間 (例: 5m。画像認証を手動で操作する時間を含む)
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"login"`
+
+	History struct {
+		// 購入履歴ページのURLのテンプレート
+		// {{.YearMonth}} (200601), {{.Year}}, {{.Month}}, {{.Page}} が使える
+		URL       string `yaml:"url"`
+		Period    string `yaml:"period"`     // 購入履歴ページの単位 (month または year)
+		FirstPage int    `yaml:"first_page"` // 最初のページ番号
+
+		// 購入履歴ページで評価するJavaScript
+		// 購入履歴の行ごとに {url, id, date, total, cash, currency, paymentMethod, coin, point, type, items} の配列を返す
+		// cash はコイン・ポイントを除いた支払額 (空の場合は total から coin と point を引いた額)、currency は通貨コード (空の場合は金額の記号から判定する)
+		// type は支払の種類 (purchase, coin, point, subscription。空の場合は purchase)
+		// 領収書のページがない行は url を空にする
+		// 1件も返さなかったページでその期間の取得を終える
+		Rows string `yaml:"rows"`
+	} `yaml:"history"`
+
+	Receipt struct {
+		Wait string `yaml:"wait"` // 領収書ページで表示を待つ要素のセレクタ
+		ID   string `yaml:"id"`   // 領収書のURLから領収書IDを取り出す正規表現 (空の場合は rows の id を使う)
+		CSS  string `yaml:"css"`  // 印刷前に追加するCSS (ナビゲーションなど領収書以外の要素を隠す)
+
+		// IDPrefix 領収書IDの前に付ける文字列 (例: global-)
+		// 同じ取得元の別のストアの領収書と、ファイル名や仕訳のIDが重ならないようにする
+		IDPrefix string `yaml:"id_prefix"`
+
+		// Addressee プロファイルの宛名を入力する要素
+		Addressee struct {
+			Field  string `yaml:"field"`  // 宛名の入力欄のセレクタ
+			Submit string `yaml:"submit"` // 宛名を反映するボタンのセレクタ (空の場合は入力するだけ)
+		} `yaml:"addressee"`
+	} `yaml:"receipt"`
+
+	historyURL *template.Template
+	idRe       *regexp.Regexp
+}
+
+// SitesDir サイト定義を置くディレクトリを取得する
+func SitesDir() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDirPath, "sites"), nil
+}
+
+// LoadSite YAMLのサイト定義を読み込む
+// path がファイルでない場合はサイト定義ディレクトリの {path}.yaml を読み込む
+func LoadSite(path string) (*Site, error) {
+	if _, err := os.Stat(path); err != nil {
+		sitesDir, err := SitesDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(sitesDir, path+".yaml")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	site := &Site{}
+	if err := yaml.Unmarshal(b, site); err != nil {
+		return nil, fmt.Errorf("サイト定義の読み込みに失敗しました %s: %w", path, err)
+	}
+	if err := site.Compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return site, nil
+}
+
+// Compile 必須項目を確認し、テンプレートと正規表現を事前に解釈しておく
+func (s *Site) Compile() error {
+	for name, value := range map[string]string{
+		"name":                 s.Name,
+		"login.url":            s.Login.URL,
+		"login.email_field":    s.Login.EmailField,
+		"login.password_field": s.Login.PasswordField,
+		"login.success":        s.Login.Success,
+		"history.url":          s.History.URL,
+		"history.rows":         s.History.Rows,
+		"receipt.wait":         s.Receipt.Wait,
+	} {
+		if value == "" {
+			return fmt.Errorf("%s は必須です", name)
+		}
+	}
+
+	if s.DisplayName == "" {
+		s.DisplayName = s.Name
+	}
+	if s.ID == "" {
+		s.ID = s.Name
+	}
+	s.Currency = strings.ToUpper(s.Currency)
+	if s.Currency == "JPY" {
+		s.Currency = ""
+	}
+	if s.Login.Captcha == "" {
+		s.Login.Captcha = defaultCaptcha
+	}
+	if s.Login.Timeout <= 0 {
+		s.Login.Timeout = defaultLoginTimeout
+	}
+	switch s.History.Period {
+	case "":
+		s.History.Period = "month"
+	case "month", "year":
+	default:
+		return fmt.Errorf("history.period は month か year を指定してください: %s", s.History.Period)
+	}
+
+	tmpl, err := template.New("history.url").Parse(s.History.URL)
+	if err != nil {
+		return fmt.Errorf("history.url のテンプレートが不正です: %w", err)
+	}
+	s.historyURL = tmpl
+
+	if s.Receipt.ID != "" {
+		re, err := regexp.Compile(s.Receipt.ID)
+		if err != nil {
+			return fmt.Errorf("receipt.id の正規表現が不正です: %w", err)
+		}
+		s.idRe = re
+	}
+
+	return nil
+}
+
+// historyPage 購入履歴ページのURLのテンプレートに渡す値
+type historyPage struct {
+	YearMonth string
+	Year      string
+	Month     string
+	Page      int
+}
+
+// HistoryURL 購入履歴ページのURLを作る
+// period: 200601 (history.period が year の場合も年月で渡す)
+func (s *Site) HistoryURL(period string, page int) (string, error) {
+	var buf bytes.Buffer
+	if err := s.historyURL.Execute(&buf, historyPage{
+		YearMonth: period,
+		Year:      period[:4],
+		Month:     period[4:],
+		Page:      page,
+	}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// receiptID 領収書のURLから領収書IDを取り出し、receipt.id_prefix を付ける
+// 領収書のページがない行 (コインのチャージなど) は rows の id、それもなければ購入日・金額・商品名から作ったIDを使う
+func (s *Site) receiptID(r row) (string, error) {
+	id, err := s.rowID(r)
+	if err != nil {
+		return "", err
+	}
+	return s.Receipt.IDPrefix + id, nil
+}
+
+// rowID 購入履歴の行の取得元での領収書ID
+func (s *Site) rowID(r row) (string, error) {
+	if r.URL == "" {
+		if r.ID != "" {
+			return r.ID, nil
+		}
+		return rowHash(r), nil
+	}
+
+	if s.idRe == nil {
+		if r.ID == "" {
+			return "", fmt.Errorf("領収書IDを取得できません: %s", r.URL)
+		}
+		return r.ID, nil
+	}
+
+	m := s.idRe.FindStringSubmatch(r.URL)
+	if len(m) < 2 {
+		return "", fmt.Errorf("領収書のURLからIDを取得できません: %s", r.URL)
+	}
+	return m[1], nil
+}
+
+// rowHash IDのない行のID (同じ行なら実行するたびに同じIDになる)
+// 同じ内容の行は同じIDになるので、取得した全ての行を uniqueIDs で区別する
+func rowHash(r row) string {
+	h := sha256.Sum256([]byte(strings.Join(append([]string{r.Type, r.Date, r.Total}, r.Items...), "\n")))
+	return fmt.Sprintf("%s-%x", r.Type, h[:6])
+}
+
+// parseMoney 購入履歴の金額を数値と通貨コードにする
+// rows が currency を返さず、金額にも通貨記号がない場合はサイト定義の currency を使う
+// 金額は決まった通貨の最小単位の整数にする (例: USDの 12.34 → 1234)
+func (s *Site) parseMoney(amount, currency string) (int, string) {
+	_, parsed := receipt.ParseMoney(amount)
+	switch {
+	case currency != "":
+		currency = strings.ToUpper(currency)
+		if currency == "JPY" {
+			currency = ""
+		}
+	case parsed != "" || strings.ContainsAny(amount, "¥￥円"):
+		currency = parsed
+	default:
+		currency = s.Currency
+	}
+	return receipt.ParseMoneyIn(amount, currency), currency
+}
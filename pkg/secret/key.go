@@ -0,0 +1,337 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+	"github.com/JINZO631/freeedom/pkg/prompt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeyFileEnv 鍵ファイルのパスを指定する環境変数
+	KeyFileEnv = "FREEEDOM_SECRET_KEY_FILE"
+	// PassphraseEnv パスフレーズを指定する環境変数 (パスフレーズを使う設定で、指定がない場合は入力させる)
+	PassphraseEnv = "FREEEDOM_SECRET_PASSPHRASE"
+
+	keySize  = 32
+	saltSize = 16
+)
+
+// ErrPassphrase パスフレーズが違う
+var ErrPassphrase = errors.New("パスフレーズが違います")
+
+var (
+	keyOnce   sync.Once
+	cachedKey []byte
+	keyErr    error
+)
+
+// loadKey 暗号化の鍵を取得する (プロセス内で1度だけ読み込む)
+// 優先順: 環境変数の鍵ファイル > secret.salt があればパスフレーズ (環境変数か入力) > secret.key (なければ作成)
+// secret.salt があるのに鍵ファイルを指定した場合と、パスフレーズが違う場合はエラーにする
+func loadKey() ([]byte, error) {
+	keyOnce.Do(func() {
+		cachedKey, keyErr = resolveKey()
+	})
+	return cachedKey, keyErr
+}
+
+func resolveKey() ([]byte, error) {
+	pendingPath, err := pendingSaltPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(pendingPath); err == nil {
+		return nil, errors.New("パスフレーズへの切り替えが途中で終わっています。freeedom secret passphrase を同じパスフレーズでもう一度実行してください")
+	}
+	return currentKey()
+}
+
+// currentKey 今使っている鍵を取得する (パスフレーズへの切り替え中でも切り替え前の鍵を返す)
+func currentKey() ([]byte, error) {
+	saltPath, err := SaltPath()
+	if err != nil {
+		return nil, err
+	}
+	salt, check, err := readSalt(saltPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	saltExists := err == nil
+
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		if saltExists {
+			return nil, fmt.Errorf("秘密情報はパスフレーズで暗号化されているので %s は使えません (%s)", KeyFileEnv, saltPath)
+		}
+		return readKeyFile(path)
+	}
+
+	if saltExists {
+		passphrase := []byte(os.Getenv(PassphraseEnv))
+		if len(passphrase) == 0 {
+			fmt.Printf("秘密情報のパスフレーズ🔑: ")
+			passphrase, err = prompt.ReadPassword()
+			fmt.Println()
+			if err != nil {
+				return nil, err
+			}
+		}
+		key, err := deriveKey(passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyKey(check, key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	keyPath, err := KeyPath()
+	if err != nil {
+		return nil, err
+	}
+	key, err := readKeyFile(keyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return createKeyFile(keyPath)
+	}
+	return key, err
+}
+
+// KeyPath 自動で作成する鍵ファイルのパス
+func KeyPath() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDirPath, "secret.key"), nil
+}
+
+// SaltPath パスフレーズから鍵を導出するソルトのパス (このファイルがあればパスフレーズを使う)
+func SaltPath() (string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDirPath, "secret.salt"), nil
+}
+
+// pendingSaltPath パスフレーズへの切り替え中に新しいソルトを置くパス (全てのファイルを暗号化し直したら secret.salt にする)
+func pendingSaltPath() (string, error) {
+	saltPath, err := SaltPath()
+	if err != nil {
+		return "", err
+	}
+	return saltPath + ".pending", nil
+}
+
+// readSalt ソルトのファイルを読み込み、ソルトと鍵の確認用の暗号文に分ける
+func readSalt(path string) (salt, check []byte, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) <= saltSize {
+		return nil, nil, fmt.Errorf("ソルトのファイルが壊れています: %s", path)
+	}
+	return b[:saltSize], b[saltSize:], nil
+}
+
+// writeSalt ソルトと、鍵が正しいか確認するための暗号文を保存する
+func writeSalt(path string, salt, key []byte) error {
+	check, err := encrypt(key, checkValue)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writePrivate(path, append(append([]byte{}, salt...), check...))
+}
+
+// checkValue 鍵が正しいか確認するために暗号化しておく値
+var checkValue = []byte("freeedom-secret-check")
+
+// verifyKey パスフレーズから導出した鍵が正しいか確認する
+func verifyKey(check, key []byte) error {
+	plain, err := decrypt(key, check)
+	if err != nil || !bytes.Equal(plain, checkValue) {
+		return ErrPassphrase
+	}
+	return nil
+}
+
+// readKeyFile 鍵ファイルを読み込む
+func readKeyFile(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("鍵ファイルの長さが %d バイトではありません: %s", keySize, path)
+	}
+	if err := enforcePermission(path); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// createKeyFile ランダムな鍵を作成して保存する
+func createKeyFile(path string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := writePrivate(path, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// deriveKey パスフレーズとソルトから scrypt で鍵を導出する
+func deriveKey(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, keySize)
+}
+
+// UsePassphrase 保存している秘密情報を、パスフレーズから導出した鍵で暗号化し直す
+// 途中で失敗しても同じパスフレーズでやり直せるように、次の順で切り替える
+//  1. 新しいソルトを secret.salt.pending に保存する (やり直す場合は保存済みのソルトを使う)
+//  2. 全てのファイルを新しい鍵で暗号化し直す (1ファイルずつ置き換える)
+//  3. secret.salt.pending を secret.salt にする
+//  4. 自動で作成した鍵ファイル secret.key を削除する
+func UsePassphrase(passphrase []byte) error {
+	if os.Getenv(KeyFileEnv) != "" {
+		return fmt.Errorf("%s で鍵ファイルを指定している場合はパスフレーズを使えません", KeyFileEnv)
+	}
+	saltPath, err := SaltPath()
+	if err != nil {
+		return err
+	}
+	pendingPath, err := pendingSaltPath()
+	if err != nil {
+		return err
+	}
+
+	oldKey, err := currentKey()
+	if err != nil {
+		return err
+	}
+
+	salt, check, err := readSalt(pendingPath)
+	var newKey []byte
+	switch {
+	case err == nil:
+		// 前回の切り替えが途中で終わっている
+		newKey, err = deriveKey(passphrase, salt)
+		if err != nil {
+			return err
+		}
+		if plain, err := decrypt(newKey, check); err != nil || !bytes.Equal(plain, checkValue) {
+			return errors.New("パスフレーズへの切り替えが途中で終わっています。前回と同じパスフレーズを入力してください")
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		newKey, err = deriveKey(passphrase, salt)
+		if err != nil {
+			return err
+		}
+		if err := writeSalt(pendingPath, salt, newKey); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if err := rekey(oldKey, newKey); err != nil {
+		return err
+	}
+	if err := os.Rename(pendingPath, saltPath); err != nil {
+		return err
+	}
+
+	keyPath, err := KeyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(keyPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	keyOnce.Do(func() {})
+	cachedKey, keyErr = newKey, nil
+	return nil
+}
+
+// rekey 設定ディレクトリの暗号化した全てのファイルを新しい鍵で暗号化し直す
+// 全て復号できることを確認してから書き換える。前回の途中で新しい鍵にしたファイルはそのままにする
+func rekey(oldKey, newKey []byte) error {
+	paths, err := encryptedFiles()
+	if err != nil {
+		return err
+	}
+
+	plains := map[string][]byte{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		plain, err := decrypt(oldKey, b)
+		if errors.Is(err, ErrDecrypt) {
+			if _, err := decrypt(newKey, b); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		plains[path] = plain
+	}
+
+	for path, plain := range plains {
+		b, err := encrypt(newKey, plain)
+		if err != nil {
+			return err
+		}
+		if err := writePrivate(path, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptedFiles 設定ディレクトリの暗号化した全てのファイル
+func encryptedFiles() ([]string, error) {
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	err = filepath.WalkDir(configDirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == Ext {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return paths, nil
+}
@@ -0,0 +1,158 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// setup 一時ディレクトリを設定ディレクトリにして、読み込んだ鍵を忘れる
+func setup(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "")
+	resetKey()
+	t.Cleanup(resetKey)
+	dir := filepath.Join(home, ".config", "freeedom")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func resetKey() {
+	keyOnce = sync.Once{}
+	cachedKey, keyErr = nil, nil
+}
+
+func TestUsePassphrase(t *testing.T) {
+	dir := setup(t)
+	path := filepath.Join(dir, "tokens", "a.json")
+	if err := Save(path, []byte("token")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UsePassphrase([]byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("secret.key が残っています: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.salt.pending")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("secret.salt.pending が残っています: %v", err)
+	}
+
+	resetKey()
+	t.Setenv(PassphraseEnv, "correct horse")
+	got, err := Load(path)
+	if err != nil || string(got) != "token" {
+		t.Fatalf("Load = %q, %v", got, err)
+	}
+
+	// 違うパスフレーズでは新しい秘密情報を保存させない
+	resetKey()
+	t.Setenv(PassphraseEnv, "wrong")
+	if err := Save(filepath.Join(dir, "tokens", "b.json"), []byte("other")); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("Save with wrong passphrase = %v, want ErrPassphrase", err)
+	}
+
+	// パスフレーズを使う設定では鍵ファイルを使えない
+	resetKey()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, make([]byte, keySize), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(KeyFileEnv, keyFile)
+	if _, err := Load(path); err == nil {
+		t.Error("Load with key file after passphrase: want error")
+	}
+	if err := UsePassphrase([]byte("x")); err == nil {
+		t.Error("UsePassphrase with key file: want error")
+	}
+}
+
+func TestUsePassphraseResume(t *testing.T) {
+	dir := setup(t)
+	paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	for _, p := range paths {
+		if err := Save(p, []byte(filepath.Base(p))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldKey, err := loadKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1ファイルだけ新しい鍵にしたところで止まった状態を作る
+	salt := make([]byte, saltSize)
+	newKey, err := deriveKey([]byte("pass"), salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSalt(filepath.Join(dir, "secret.salt.pending"), salt, newKey); err != nil {
+		t.Fatal(err)
+	}
+	b, err := encrypt(newKey, []byte("a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePrivate(paths[0]+Ext, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// 途中の状態では古い鍵で保存させない
+	resetKey()
+	if err := Save(filepath.Join(dir, "c.json"), []byte("c")); err == nil {
+		t.Error("Save during pending switch: want error")
+	}
+
+	resetKey()
+	if err := UsePassphrase([]byte("other")); err == nil {
+		t.Error("resume with different passphrase: want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.key")); err != nil {
+		t.Fatalf("secret.key was removed before the switch finished: %v", err)
+	}
+
+	resetKey()
+	if err := UsePassphrase([]byte("pass")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		got, err := Load(p)
+		if err != nil || string(got) != filepath.Base(p) {
+			t.Errorf("Load(%s) = %q, %v", p, got, err)
+		}
+	}
+	if _, err := decrypt(oldKey, mustRead(t, paths[1]+Ext)); err == nil {
+		t.Error("b.json is still encrypted with the old key")
+	}
+}
+
+// TestSaltWithoutCheck 鍵の確認用の暗号文がないソルトは壊れたファイルとして扱う
+func TestSaltWithoutCheck(t *testing.T) {
+	dir := setup(t)
+	if err := os.WriteFile(filepath.Join(dir, "secret.salt"), make([]byte, saltSize), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	if _, err := loadKey(); err == nil || errors.Is(err, ErrPassphrase) {
+		t.Errorf("loadKey() error = %v, want a broken salt file error", err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
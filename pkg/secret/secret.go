@@ -0,0 +1,253 @@
+// Package secret トークン・パスワード・Cookieなどの秘密情報を暗号化して設定ディレクトリに保存する
+//
+// 秘密情報は保存先のパスに .enc を付けたファイルに、AES-256-GCMで暗号化して保存する。
+// 鍵は設定ディレクトリの secret.key (初回に自動で作成する) か、パスフレーズから scrypt で導出する。
+// 暗号化する前に平文で保存していたファイルは、読み込んだときに暗号化して置き換える。
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/configdir"
+)
+
+// Ext 暗号化したファイルの拡張子
+const Ext = ".enc"
+
+// magic 暗号化したファイルの先頭に付ける識別子
+var magic = []byte("freeedom-secret-v1\n")
+
+// ErrDecrypt 復号に失敗した (鍵やパスフレーズが違うか、ファイルが壊れている)
+var ErrDecrypt = errors.New("秘密情報を復号できませんでした。鍵かパスフレーズが違う可能性があります")
+
+// Save 秘密情報を暗号化して path.enc に保存する (パーミッションは 0600)
+// 平文の path が残っていれば削除する
+func Save(path string, data []byte) error {
+	key, err := loadKey()
+	if err != nil {
+		return err
+	}
+	encrypted, err := encrypt(key, data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := writePrivate(path+Ext, encrypted); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Load path.enc を復号して返す
+// path.enc がなく平文の path がある場合は、平文を読み込んで暗号化して保存し直す
+// どちらもない場合は fs.ErrNotExist のエラーを返す
+func Load(path string) ([]byte, error) {
+	encrypted, err := os.ReadFile(path + Ext)
+	if errors.Is(err, fs.ErrNotExist) {
+		return migrate(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := enforcePermission(path + Ext); err != nil {
+		return nil, err
+	}
+
+	key, err := loadKey()
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(key, encrypted)
+}
+
+// Exists 秘密情報 (暗号化したファイルか平文のファイル) が保存されているか
+func Exists(path string) (bool, error) {
+	for _, p := range []string{path + Ext, path} {
+		_, err := os.Stat(p)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Remove 秘密情報を削除する (暗号化したファイルと平文のファイルの両方)
+// どちらもない場合は fs.ErrNotExist のエラーを返す
+func Remove(path string) error {
+	removed := false
+	for _, p := range []string{path + Ext, path} {
+		err := os.Remove(p)
+		if err == nil {
+			removed = true
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if !removed {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// SaveJSON 値をJSONにして暗号化して保存する
+func SaveJSON(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return Save(path, b)
+}
+
+// LoadJSON 暗号化して保存したJSONを読み込む
+func LoadJSON(path string, v any) error {
+	b, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("秘密情報を読み込めませんでした %s: %w", path, err)
+	}
+	return nil
+}
+
+// migrate 平文で保存していたファイルを暗号化して置き換える
+func migrate(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := Save(path, data); err != nil {
+		return nil, fmt.Errorf("平文の秘密情報を暗号化できませんでした %s: %w", path, err)
+	}
+	fmt.Println("平文で保存されていた秘密情報を暗号化しました:", path+Ext)
+	return data, nil
+}
+
+// encrypt AES-256-GCMで暗号化する (識別子 + nonce + 暗号文)
+func encrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, magic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, magic), nil
+}
+
+// decrypt encrypt で暗号化したデータを復号する
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(magic)+gcm.NonceSize() || string(data[:len(magic)]) != string(magic) {
+		return nil, errors.New("暗号化した秘密情報のファイルではありません")
+	}
+
+	data = data[len(magic):]
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writePrivate 所有者だけが読み書きできるパーミッション (0600) でファイルを書き込む
+// 同じディレクトリの一時ファイルに書き込んでから置き換えるので、途中で失敗しても元のファイルは壊れない
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 置き換えた後は存在しないので何もしない
+
+	if err := tmp.Chmod(0o600); err != nil && runtime.GOOS != "windows" {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// enforcePermission 所有者以外も読めるパーミッションになっていれば 0600 に直す
+func enforcePermission(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o077 == 0 {
+		return nil
+	}
+	return os.Chmod(path, 0o600)
+}
+
+// SitePath サイトごとの秘密情報の保存先 (設定ディレクトリの {kind}/{サイト名}.json)
+// account を指定した場合は {kind}/{サイト名}/{account}.json
+func SitePath(kind, site, account string) (string, error) {
+	for _, name := range []string{site, account} {
+		if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return "", fmt.Errorf("ファイル名に使えない名前です: %s", name)
+		}
+	}
+	if site == "" {
+		return "", errors.New("サイト名を指定してください")
+	}
+
+	configDirPath, err := configdir.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if account == "" {
+		return filepath.Join(configDirPath, kind, site+".json"), nil
+	}
+	return filepath.Join(configDirPath, kind, site, account+".json"), nil
+}
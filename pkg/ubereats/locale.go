@@ -0,0 +1,142 @@
+package ubereats
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/JINZO631/freeedom/pkg/extract"
+	"github.com/JINZO631/freeedom/pkg/mailsource"
+	"golang.org/x/net/html"
+)
+
+// locale 言語ごとの領収書メールの読み取り方
+type locale struct {
+	Lang string // 言語 (ja, en)
+
+	// Queries 領収書の種類ごとのメールの検索条件
+	Queries map[string]*mailsource.Query
+
+	PDFLinkTexts []string        // 領収書PDFのリンクのテキスト
+	Dates        []dateFormat    // 支払日の書式
+	TotalLabel   string          // 合計金額のラベル
+	TotalLabels  map[string]bool // 料金の内訳に含めない合計の行のラベル
+	Distance     *regexp.Regexp  // 乗車距離 (1つ目のグループが数値、2つ目が単位)
+}
+
+// dateFormat 支払日の書式 (正規表現で探した文字列を time.Parse で読む)
+type dateFormat struct {
+	re      *regexp.Regexp
+	layouts []string
+}
+
+// monthNames 英語の月名 (省略形を含む)
+const monthNames = `(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\.?`
+
+// locales 対応している言語 (言語を判定できなかった場合は先頭を使う)
+var locales = []*locale{
+	{
+		Lang: "ja",
+		Queries: map[string]*mailsource.Query{
+			Eats: {Subject: Subject},
+			// 乗車の領収書の件名は「〜のご乗車に関する領収書」のように曜日や時間帯を含むので差出人と合わせて絞り込む
+			Rides: {Subject: "乗車", From: "uber.com"},
+		},
+		PDFLinkTexts: []string{"この PDF をダウンロードしてください", "PDF をダウンロードする >"},
+		Dates: []dateFormat{
+			{regexp.MustCompile(`\d{4}年\d{1,2}月\d{1,2}日`), []string{"2006年1月2日"}},
+		},
+		TotalLabel:  "合計",
+		TotalLabels: map[string]bool{"合計": true, "小計": true},
+		Distance:    regexp.MustCompile(`([\d.]+)\s*(キロメートル|km|マイル)`),
+	},
+	{
+		Lang: "en",
+		Queries: map[string]*mailsource.Query{
+			// 例: Your Tuesday evening order with Uber Eats
+			Eats: {Subject: "order with Uber Eats", From: "uber.com"},
+			// 例: Your Thursday evening trip with Uber
+			Rides: {Subject: "trip with Uber", From: "uber.com"},
+		},
+		PDFLinkTexts: []string{"Download PDF", "Download PDF >", "download this PDF"},
+		Dates: []dateFormat{
+			{regexp.MustCompile(monthNames + ` \d{1,2}, \d{4}`), []string{"January 2, 2006", "Jan 2, 2006", "Jan. 2, 2006"}},
+			{regexp.MustCompile(`\d{1,2} ` + monthNames + ` \d{4}`), []string{"2 January 2006", "2 Jan 2006", "2 Jan. 2006"}},
+		},
+		TotalLabel:  "Total",
+		TotalLabels: map[string]bool{"Total": true, "Subtotal": true},
+		Distance:    regexp.MustCompile(`([\d.]+)\s*(kilometers|km|miles|mi)\b`),
+	},
+}
+
+// detectLocale メールの言語を判定する
+// html要素のlang属性があればそれを使い、なければ本文にひらがな・カタカナが含まれているかで判定する
+func detectLocale(doc *html.Node, lines []string) *locale {
+	if lang := htmlLang(doc); lang != "" {
+		for _, l := range locales {
+			if strings.HasPrefix(strings.ToLower(lang), l.Lang) {
+				return l
+			}
+		}
+	}
+
+	for _, line := range lines {
+		for _, r := range line {
+			if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+				return findLocale("ja")
+			}
+		}
+	}
+	if len(lines) > 0 {
+		return findLocale("en")
+	}
+	return locales[0]
+}
+
+// findLocale 言語の読み取り方を返す
+func findLocale(lang string) *locale {
+	for _, l := range locales {
+		if l.Lang == lang {
+			return l
+		}
+	}
+	return locales[0]
+}
+
+var htmlLangSel = extract.MustCompile("html[lang]")
+
+// htmlLang html要素のlang属性を返す
+func htmlLang(doc *html.Node) string {
+	return extract.Attr(htmlLangSel.First(doc), "lang")
+}
+
+// isPDFLinkText リンクのテキストが領収書PDFのリンクかどうか
+func (l *locale) isPDFLinkText(text string) bool {
+	for _, t := range l.PDFLinkTexts {
+		if strings.EqualFold(strings.TrimSpace(text), t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDate テキストから支払日を探して yyyy-mm-dd に変換する
+// 見つからない場合は空文字を返す
+func (l *locale) parseDate(text string) string {
+	for _, f := range l.Dates {
+		s := f.re.FindString(text)
+		if s == "" {
+			continue
+		}
+		for _, layout := range f.layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format("2006-01-02")
+			}
+		}
+	}
+	return ""
+}
+
+// amountRe 金額 (例: ￥1,234, -¥100, JP¥500, $12.34)
+var amountRe = regexp.MustCompile(`-?\s*(?:[A-Z]{1,3}\s?)?[￥¥$€£]\s*[\d,]+(?:\.\d+)?`)
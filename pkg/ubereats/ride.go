@@ -0,0 +1,70 @@
+package ubereats
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/JINZO631/freeedom/pkg/extract"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+// rideTimeRe 乗車・降車時刻の行 (例: 10:05, 午後 10:05, 10:05 PM)
+var rideTimeRe = regexp.MustCompile(`^(?:午前|午後)?\s*\d{1,2}:\d{2}(?:\s*[AaPp]\.?[Mm]\.?)?$`)
+
+// amountLineRe 金額だけの行
+var amountLineRe = regexp.MustCompile(`^` + amountRe.String() + `$`)
+
+// extractRide 乗車の領収書メールから乗車地・降車地・距離・料金の内訳を取り出す
+func extractRide(lines []string, loc *locale) *receipt.Ride {
+	ride := &receipt.Ride{}
+
+	// 時刻の次の行が住所 (1つ目が乗車地、2つ目が降車地)
+	stops := 0
+	for i := 0; i+1 < len(lines) && stops < 2; i++ {
+		if !rideTimeRe.MatchString(lines[i]) {
+			continue
+		}
+		if stops == 0 {
+			ride.PickupTime, ride.Pickup = lines[i], lines[i+1]
+		} else {
+			ride.DropoffTime, ride.Dropoff = lines[i], lines[i+1]
+		}
+		stops++
+		i++
+	}
+
+	if m := loc.Distance.FindStringSubmatch(strings.Join(lines, " ")); m != nil {
+		ride.Distance = m[1] + " " + distanceUnits[m[2]]
+	}
+
+	// 金額だけの行とその前の行を料金の内訳として扱う
+	for i := 1; i < len(lines); i++ {
+		if !amountLineRe.MatchString(lines[i]) || loc.TotalLabels[lines[i-1]] || amountLineRe.MatchString(lines[i-1]) {
+			continue
+		}
+		amount, _ := receipt.ParseMoney(lines[i])
+		ride.Fares = append(ride.Fares, receipt.Fare{Label: lines[i-1], Amount: amount})
+	}
+
+	return ride
+}
+
+// distanceUnits 距離の単位の表記ゆれ
+var distanceUnits = map[string]string{
+	"キロメートル":     "km",
+	"km":         "km",
+	"kilometers": "km",
+	"マイル":        "mi",
+	"miles":      "mi",
+	"mi":         "mi",
+}
+
+// findTotal メール本文のテキストから合計金額と通貨コードを探す
+// 合計のラベルと同じ行か、続く2行以内にある金額を合計金額とする
+func findTotal(lines []string, loc *locale) (int, string) {
+	total := extract.ValueNear(lines, loc.TotalLabel, amountRe, 2)
+	if total == "" {
+		return 0, ""
+	}
+	return receipt.ParseMoney(total)
+}
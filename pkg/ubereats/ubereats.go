@@ -3,77 +3,102 @@ package ubereats
 import (
 	"bufio"
 	"context"
-	"crypto/rand"
-	"encoding/base64"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
-	"regexp"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/JINZO631/freeedom/pkg/browser"
+	"github.com/JINZO631/freeedom/pkg/extract"
+	"github.com/JINZO631/freeedom/pkg/invoice"
+	"github.com/JINZO631/freeedom/pkg/mailsource"
+	"github.com/JINZO631/freeedom/pkg/receipt"
 	"github.com/chromedp/chromedp"
 	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/net/html"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/gmail/v1"
-	"google.golang.org/api/option"
 )
 
-// Run GmailからUberEatsの領収書のメールを探してダウンロードする
-func Run(ctx context.Context, oauthClientJSONPath, afterDate, beforeDate string) error {
+// Provider 領収書の取得元の名前
+const Provider = "ubereats"
 
-	// Gmailの設定を取得
-	b, err := os.ReadFile(oauthClientJSONPath)
-	if err != nil {
-		return err
-	}
-	config, err := google.ConfigFromJSON(b, gmail.GmailReadonlyScope)
-	if err != nil {
-		return err
-	}
+// 領収書の種類
+const (
+	Eats  = "eats"  // Uber Eatsの注文
+	Rides = "rides" // Uberの乗車
+)
 
-	// リダイレクト先のURLを設定
-	config.RedirectURL = "http://localhost:8080/callback"
+// Subject UberEatsの領収書メールの件名
+const Subject = "Uber の領収書"
 
-	// トークンが保存されているか確認し、保存されている場合はそれを使う
-	token, err := GetToken(ctx, config)
-	if err != nil {
-		return err
-	}
+// vendors 領収書の種類ごとの発行者
+var vendors = map[string]string{
+	Eats:  "Uber Eats Japan合同会社",
+	Rides: "Uber Japan株式会社",
+}
 
-	token, err = RefreshToken(ctx, config, token)
-	if err != nil {
-		return err
-	}
+// Types 対応している領収書の種類
+func Types() []string {
+	return []string{Eats, Rides}
+}
 
-	// Gmailサービスを作成
-	client := config.Client(ctx, token)
-	gmailService, err := gmail.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return err
+// Run メールボックスからUberEats・Uberの乗車の領収書のメールを探し、メタデータを保存してPDFをダウンロードする
+// types: 対象にする領収書の種類 (eats, rides)
+func Run(ctx context.Context, src mailsource.Source, types []string, afterDate, beforeDate, outputDir string) error {
+	for _, t := range types {
+		if !slices.Contains(Types(), t) {
+			return fmt.Errorf("対応していない領収書の種類です: %s (対応: %v)", t, Types())
+		}
 	}
 
-	// UberEatsの領収書のメールを探す
-	fmt.Println("UberEatsの領収書のメールを探します。")
-	query := fmt.Sprintf("after:%s before:%s subject:%s", afterDate, beforeDate, "Uber の領収書")
-	fmt.Println("query: ", query)
+	// 種類ごとに領収書のメールを探す
+	// 同じメールが複数の種類の条件に一致した場合は乗車の領収書として扱う
+	mails := []*mailsource.Message{}
+	mailTypes := map[mailsource.MessageKey]string{}
+	for _, t := range []string{Rides, Eats} {
+		if !slices.Contains(types, t) {
+			continue
+		}
 
-	// メール取得開始
-	mails, err := getEmails(gmailService, query)
-	if err != nil {
-		return err
-	}
+		// 言語ごとに件名が異なるので、対応している言語の検索条件で全て探す
+		for _, loc := range locales {
+			fmt.Printf("Uberの領収書のメールを探します。 (%s, %s)\n", t, loc.Lang)
+			q := *loc.Queries[t]
+			q.After = afterDate
+			q.Before = beforeDate
 
-	fmt.Println("メールを取得しました。 取得数: ", len(mails))
+			// メール取得開始
+			found, err := src.Search(ctx, &q)
+			if err != nil {
+				return err
+			}
 
-	// メールから領収書PDFのリンクを取り出す
+			fmt.Println("メールを取得しました。 取得数: ", len(found))
+			for _, mail := range found {
+				if _, ok := mailTypes[mail.Key()]; ok {
+					continue
+				}
+				mailTypes[mail.Key()] = t
+				mails = append(mails, mail)
+			}
+		}
+	}
+
+	// メールから領収書PDFのリンクと領収書の情報を取り出す
 	pdfLinks := []*PDFLink{}
+	receipts := map[mailsource.MessageKey]*receipt.Receipt{} // メールと領収書のメタデータ
+	report := receipt.NewReport(Provider, afterDate, beforeDate)
 	for i, mail := range mails {
-		pdfLink, err := extractPDFLink(mail)
+		pdfLink, err := extractPDFLink(mail, mailTypes[mail.Key()])
 		if err != nil {
-			fmt.Println(color.RedString("×"), i, mail.Id)
+			fmt.Println(color.RedString("×"), i, mail.ID)
+			if err := mailsource.Mark(ctx, src, mail.Key(), false); err != nil {
+				return err
+			}
 
 			// PDFリンクが見つからなかった場合はファイルとして保存しておく
 			var pdfLinkNotFound *pdfLinkNotFound
@@ -87,31 +112,63 @@ func Run(ctx context.Context, oauthClientJSONPath, afterDate, beforeDate string)
 			} else {
 				return err
 			}
-		} else {
-			// fmt.Println(color.GreenString("✓"), i, mail.Id)
+			continue
+		} else if pdfLink.Currency != "" {
+			// 外貨建ての領収書は円に換算してから仕訳にする必要がある
+			fmt.Println(color.YellowString("!"), i, mail.ID, pdfLink.Date, "外貨建ての領収書です:", receipt.FormatMoney(pdfLink.Total, pdfLink.Currency))
+		} else if pdfLink.RegistrationNumber == "" && pdfLink.Lang == "ja" {
+			// 登録番号の記載がない領収書は適格請求書として扱えない可能性がある
+			fmt.Println(color.YellowString("!"), i, mail.ID, pdfLink.Date, "登録番号が見つかりません")
+		}
+
+		// 領収書の情報をメタデータとして保存する
+		r := pdfLink.receipt()
+		if _, err := receipt.WriteSidecar(pdfPath(outputDir, r.ID), r); err != nil {
+			return err
+		}
+		report.Add(r)
+
+		if pdfLink.URL == "" {
+			// PDFのリンクがない領収書はメタデータを保存したら処理済みにする
+			if err := mailsource.Mark(ctx, src, mail.Key(), true); err != nil {
+				return err
+			}
+			continue
 		}
 		pdfLinks = append(pdfLinks, pdfLink)
+		receipts[mail.Key()] = r
 	}
 
 	// Chromeを自動操作してPDFをダウンロードする
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", false),
-	)
-	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
-	defer cancel()
-
-	chromedpCtx, cancel := chromedp.NewContext(allocCtx)
+	chromedpCtx, cancel := browser.NewContext(ctx)
 	defer cancel()
 
+	// PDFを保存できたメールだけを処理済みにし、失敗したメールは次回の実行でもう一度処理する
 	fmt.Println("Chromeを自動操作してPDFをダウンロードします。")
+	failed := 0
 	bar := progressbar.Default(int64(len(pdfLinks)))
 	for i, pdfLink := range pdfLinks {
+		r := receipts[pdfLink.key()]
+		path := pdfPath(outputDir, r.ID)
 
+		var err error
 		if i == 0 {
 			// 初回のみログイン操作が必要なため処理を変える
-			downloadFirstPDF(chromedpCtx, pdfLink)
+			err = downloadFirstPDF(chromedpCtx, pdfLink, path)
 		} else {
-			downloadPDF(chromedpCtx, pdfLink)
+			err = downloadPDF(chromedpCtx, pdfLink, path)
+		}
+		if err == nil {
+			r.PDFFile = filepath.Base(path)
+			_, err = receipt.WriteSidecar(path, r)
+		}
+		saved := err == nil
+		if !saved {
+			failed++
+			fmt.Println(color.RedString("×"), pdfLink.ID, err)
+		}
+		if err := mailsource.Mark(ctx, src, pdfLink.key(), saved); err != nil {
+			return err
 		}
 
 		// TooManyRequestsを回避するために待機する
@@ -120,155 +177,153 @@ func Run(ctx context.Context, oauthClientJSONPath, afterDate, beforeDate string)
 		bar.Add(1)
 	}
 
-	fmt.Println("PDFのダウンロードが完了しました。")
-	return nil
-}
-
-// generateRandomState OAuth2用のランダムなstate文字列を生成する
-func generateRandomState() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
+	reportPath, err := report.Write(outputDir)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return base64.StdEncoding.EncodeToString(b), nil
+	fmt.Println("レポートを保存しました:", reportPath)
+
+	if failed > 0 {
+		// 差分同期の記録も進めずに、次回の実行でもう一度取得する
+		return fmt.Errorf("PDFをダウンロードできなかった領収書があります: %d件", failed)
+	}
+	fmt.Println("PDFのダウンロードが完了しました。")
+
+	// 差分同期の場合は次回の実行のために処理したところまでを記録する
+	// PDFのダウンロードを終える前に記録すると、失敗したメールを次回に取得できなくなる
+	return mailsource.Checkpoint(ctx, src)
 }
 
-// getEmails クエリにマッチするメールを取得する
-func getEmails(srv *gmail.Service, query string) ([]*gmail.Message, error) {
-	var messages []*gmail.Message
+// PDFLink PDFリンクと支払日の情報を持つ構造体
+type PDFLink struct {
+	ID   string // メールのID
+	Type string // 領収書の種類
+	URL  string // 乗車の領収書ではPDFのリンクがない場合がある
+	Date string
 
-	req := srv.Users.Messages.List("me").Q(query)
-	for {
-		res, err := req.Do()
-		if err != nil {
-			return nil, err
-		}
+	// 適格請求書発行事業者の登録番号 (メール本文に記載がない場合は空)
+	RegistrationNumber string
 
-		messages = append(messages, res.Messages...)
-		if res.NextPageToken == "" {
-			break
-		}
+	Account  string        // メールを取得したアカウントのメールアドレス
+	Lang     string        // メールの言語
+	Total    int           // 合計金額
+	Currency string        // 通貨コード (日本円の場合は空)
+	Ride     *receipt.Ride // 乗車の詳細 (乗車の領収書のみ)
+}
 
-		req.PageToken(res.NextPageToken)
-	}
+// key 領収書を取り出したメールを識別する値
+func (l *PDFLink) key() mailsource.MessageKey {
+	return mailsource.MessageKey{Account: l.Account, ID: l.ID}
+}
 
-	fullMessages := make([]*gmail.Message, len(messages))
-	count := len(messages)
-	bar := progressbar.Default(int64(count))
-	for i, message := range messages {
-		fullMessage, err := srv.Users.Messages.Get("me", message.Id).Do()
-		if err != nil {
-			return nil, err
-		}
-		fullMessages[i] = fullMessage
-		bar.Add(1)
+// receiptID 領収書のID (ファイル名と仕訳の管理番号に使う)
+// メールのIDはアカウントごとに振られるので、複数のアカウントから取得しても重ならないようにアカウントのハッシュを付ける
+func (l *PDFLink) receiptID() string {
+	if l.Account == "" {
+		return l.ID
 	}
-
-	return fullMessages, nil
+	h := sha256.Sum256([]byte(l.Account))
+	return fmt.Sprintf("%x-%s", h[:4], l.ID)
 }
 
-// PDFLink PDFリンクと支払日の情報を持つ構造体
-type PDFLink struct {
-	URL  string
-	Date string
+// receipt 領収書のメタデータにする
+func (l *PDFLink) receipt() *receipt.Receipt {
+	return &receipt.Receipt{
+		Provider:           Provider,
+		ID:                 l.receiptID(),
+		Type:               l.Type,
+		URL:                l.URL,
+		Vendor:             vendors[l.Type],
+		Date:               l.Date,
+		Total:              l.Total,
+		Currency:           l.Currency,
+		Account:            l.Account,
+		RegistrationNumber: l.RegistrationNumber,
+		Ride:               l.Ride,
+	}
 }
 
 // extractPDFLink メールからPDFのリンクを抽出する
-func extractPDFLink(message *gmail.Message) (*PDFLink, error) {
+func extractPDFLink(message *mailsource.Message, receiptType string) (*PDFLink, error) {
 
-	// base64された本文htmlをデコードする
-	data, err := base64.URLEncoding.DecodeString(message.Payload.Body.Data)
-	if err != nil {
-		return nil, err
-	}
-	htmlContent := string(data)
+	htmlContent := message.HTML
 
 	// htmlをパースする
-	r := strings.NewReader(htmlContent)
-	doc, err := html.Parse(r)
+	doc, err := extract.Parse(htmlContent)
 	if err != nil {
 		return nil, err
 	}
 
+	// メールの言語を判定する
+	lines := extract.Lines(doc)
+	loc := detectLocale(doc, lines)
+
 	// PDFのリンクを抽出する
-	pdfURL := findPDFLink(doc)
-	if pdfURL == "" {
+	pdfURL := findPDFLink(doc, loc)
+	if pdfURL == "" && receiptType != Rides {
 
 		// PDFのリンクが見つからなかった場合はエラーを返す
 		return nil, &pdfLinkNotFound{
 			Message:     "メール本文からPDFリンクが見つかりません",
-			ID:          message.Id,
+			ID:          message.ID,
 			HTMLContent: htmlContent,
 		}
 	}
 
 	// 支払日を取得する
-	date := findPaymentDate(doc)
+	date := findPaymentDate(doc, lines, loc)
 	if date == "" {
-		return nil, errors.New("メール本文から支払日が見つかりません")
+		return nil, fmt.Errorf("メール本文から支払日が見つかりません (言語: %s)", loc.Lang)
+	}
+
+	// 登録番号を取得する
+	registrationNumber, _ := invoice.Extract(strings.Join(lines, " "))
+
+	pdfLink := &PDFLink{
+		ID:                 message.ID,
+		Type:               receiptType,
+		URL:                pdfURL,
+		Date:               date,
+		RegistrationNumber: registrationNumber,
+		Lang:               loc.Lang,
+		Account:            message.Account,
+	}
+	pdfLink.Total, pdfLink.Currency = findTotal(lines, loc)
+
+	// 乗車の領収書は乗車地・降車地・距離・料金の内訳も取り出す
+	if receiptType == Rides {
+		pdfLink.Ride = extractRide(lines, loc)
 	}
 
 	// PDFのリンクと支払日を返す
-	return &PDFLink{
-		URL:  pdfURL,
-		Date: date,
-	}, nil
+	return pdfLink, nil
 }
 
-// findPDFLink メール本文のhtmlからPDFのリンクを探す
-func findPDFLink(n *html.Node) string {
-	// タグが<a>である要素を探す
-	if n.Type == html.ElementNode && n.Data == "a" {
-		// リンクのテキストが「この PDF をダウンロードしてください」 or 「PDF をダウンロードする >」であるかどうかを確認
-		text := n.FirstChild.Data
-		switch text {
-		case "この PDF をダウンロードしてください", "PDF をダウンロードする >":
-			// href属性を取得
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					return a.Val
-				}
-			}
-		}
-	}
+var (
+	linkSel = extract.MustCompile("a[href]")
+	spanSel = extract.MustCompile("span")
+)
 
-	// 子ノードを再帰的に探索
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		link := findPDFLink(c)
-		if link != "" {
-			return link
+// findPDFLink メール本文のhtmlからPDFのリンクを探す
+func findPDFLink(doc *html.Node, loc *locale) string {
+	// リンクのテキストが言語ごとのPDFのリンクのテキスト (例: 「PDF をダウンロードする >」) であるかどうかを確認
+	for _, a := range linkSel.All(doc) {
+		if loc.isPDFLinkText(extract.Text(a)) {
+			return extract.Attr(a, "href")
 		}
 	}
-
 	return ""
 }
 
 // findPaymentDate メール本文のhtmlから支払日を探す
-func findPaymentDate(n *html.Node) string {
-	// タグが<span>である要素を探す
-	if n.Type == html.ElementNode && n.Data == "span" {
-		// リンクのテキストがyyyy年mm月dd日であるかどうかを確認
-		text := n.FirstChild.Data
-		re := regexp.MustCompile(`\d{4}年\d{1,2}月\d{1,2}日`)
-		if re.MatchString(text) {
-			// yyyy年mm月dd日をyyyy-mm-ddに変換
-			text = strings.Replace(text, "年", "-", 1)
-			text = strings.Replace(text, "月", "-", 1)
-			text = strings.Replace(text, "日", "", 1)
-			return text
-		}
-	}
-
-	// 子ノードを再帰的に探索
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		date := findPaymentDate(c)
-		if date != "" {
+// 言語ごとの日付の書式 (例: yyyy年mm月dd日, January 2, 2006) の<span>を優先し、なければ本文全体から探す
+func findPaymentDate(doc *html.Node, lines []string, loc *locale) string {
+	for _, span := range spanSel.All(doc) {
+		if date := loc.parseDate(extract.Text(span)); date != "" {
 			return date
 		}
 	}
-
-	return ""
+	return loc.parseDate(strings.Join(lines, " "))
 }
 
 // pdfLinkNotFound PDFリンクが見つからなかった場合のエラー
@@ -297,21 +352,27 @@ func writePDFLinkNotFoundHTML(pdfLinkNotFound *pdfLinkNotFound) (string, error)
 	return fileName, nil
 }
 
-// downloadFirstPDF 初回のPDFをダウンロードする
-func downloadFirstPDF(ctx context.Context, pdfLink *PDFLink) {
+// pdfPath 領収書PDFの保存先 (メタデータのJSONと同じ名前にする)
+func pdfPath(outputDir, id string) string {
+	return filepath.Join(outputDir, Provider+"_"+id+".pdf")
+}
 
-	chromedp.Run(ctx,
+// downloadFirstPDF 初回のPDFをダウンロードする
+// 初回はUberへのログインが必要なので、ログインを終えてからダウンロードする
+func downloadFirstPDF(ctx context.Context, pdfLink *PDFLink, path string) error {
+	if err := chromedp.Run(ctx,
+		browser.DenyDownloads(),
 		chromedp.Navigate(pdfLink.URL),
-	)
-	fmt.Printf("初回はUberEatsのログイン操作が必要です、Chromeでのダウンロードが完了したらEnterを押して処理を続行してください。")
+	); err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
+		return err
+	}
+	fmt.Printf("初回はUberEatsのログイン操作が必要です、Chromeでログインが完了したらEnterを押して処理を続行してください。")
 
 	bufio.NewScanner(os.Stdin).Scan()
+	return downloadPDF(ctx, pdfLink, path)
 }
 
-// downloadPDF PDFをダウンロードする
-func downloadPDF(ctx context.Context, pdfLink *PDFLink) {
-
-	chromedp.Run(ctx,
-		chromedp.Navigate(pdfLink.URL),
-	)
+// downloadPDF PDFをダウンロードして path に保存する
+func downloadPDF(ctx context.Context, pdfLink *PDFLink, path string) error {
+	return browser.Download(ctx, pdfLink.URL, path)
 }
@@ -0,0 +1,140 @@
+package ubereats
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/JINZO631/freeedom/pkg/mailsource"
+	"github.com/JINZO631/freeedom/pkg/receipt"
+)
+
+func TestExtractPDFLink(t *testing.T) {
+	for _, tt := range []struct {
+		file        string
+		receiptType string
+		want        *PDFLink
+	}{
+		{"eats_ja.html", Eats, &PDFLink{
+			URL:                "https://www.uber.com/receipt/pdf/abc123",
+			Date:               "2024-03-09",
+			RegistrationNumber: "T9234567890123",
+			Lang:               "ja",
+			Total:              2480,
+		}},
+		{"eats_en.html", Eats, &PDFLink{
+			URL:      "https://www.uber.com/receipt/pdf/def456",
+			Date:     "2024-03-09",
+			Lang:     "en",
+			Total:    2345, // 外貨はセント単位
+			Currency: "USD",
+		}},
+		{"ride_ja.html", Rides, &PDFLink{
+			Date:  "2024-04-01",
+			Lang:  "ja",
+			Total: 1850,
+			Ride: &receipt.Ride{
+				Pickup:      "東京都渋谷区道玄坂1-2-3",
+				PickupTime:  "午後 10:05",
+				Dropoff:     "東京都港区六本木4-5-6",
+				DropoffTime: "午後 10:17",
+				Distance:    "3.20 km",
+				Fares: []receipt.Fare{
+					{Label: "乗車料金", Amount: 1650},
+					{Label: "迎車料金", Amount: 200},
+				},
+			},
+		}},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			message := &mailsource.Message{ID: "m1", HTML: readFile(t, tt.file)}
+			got, err := extractPDFLink(message, tt.receiptType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.ID = "m1"
+			tt.want.Type = tt.receiptType
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractPDFLink = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReceiptID 別のアカウントの同じIDのメールが別の領収書になることを確認する
+func TestReceiptID(t *testing.T) {
+	if got := (&PDFLink{ID: "m1"}).receipt().ID; got != "m1" {
+		t.Errorf("receipt ID without account = %q, want m1", got)
+	}
+
+	a := (&PDFLink{ID: "m1", Account: "a@example.com"}).receipt()
+	b := (&PDFLink{ID: "m1", Account: "b@example.com"}).receipt()
+	if a.ID == b.ID {
+		t.Errorf("receipt IDs of different accounts are the same: %q", a.ID)
+	}
+	if !strings.HasSuffix(a.ID, "-m1") || strings.Contains(a.ID, "@") {
+		t.Errorf("receipt ID = %q, want <account hash>-m1", a.ID)
+	}
+	if pdfPath("out", a.ID) == pdfPath("out", b.ID) {
+		t.Errorf("PDF paths of different accounts are the same: %q", pdfPath("out", a.ID))
+	}
+}
+
+func TestExtractPDFLinkNotFound(t *testing.T) {
+	message := &mailsource.Message{ID: "m1", HTML: `<p>2024年3月9日</p><a href="x"></a>`}
+	if _, err := extractPDFLink(message, Eats); err == nil {
+		t.Error("want error")
+	}
+}
+
+var dateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// FuzzExtractPDFLink 壊れたhtmlや空の要素でもpanicせず、見つけた支払日が yyyy-mm-dd になっていることを確認する
+func FuzzExtractPDFLink(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(b), false)
+		f.Add(string(b), true)
+	}
+	f.Add(`<a href="x">PDF をダウンロードする &gt;</a><span></span>`, false)
+	f.Add(`<html lang="en"><span>February 30, 2024</span>`, true)
+
+	f.Fuzz(func(t *testing.T, s string, ride bool) {
+		receiptType := Eats
+		if ride {
+			receiptType = Rides
+		}
+		got, err := extractPDFLink(&mailsource.Message{ID: "m1", HTML: s}, receiptType)
+		if err != nil {
+			return
+		}
+		if !dateRe.MatchString(got.Date) {
+			t.Errorf("Date = %q", got.Date)
+		}
+		if got.URL == "" && receiptType != Rides {
+			t.Error("URL is empty")
+		}
+		if ride && got.Ride == nil {
+			t.Error("Ride is nil")
+		}
+	})
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}